@@ -0,0 +1,579 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dunkod/config"
+	"dunkod/db"
+	"dunkod/jobs"
+	"dunkod/nba"
+	"dunkod/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+const apiPrefix = "/api/v1"
+
+type APIError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Status, e.Message)
+}
+
+func newAPIError(status int, message string) *APIError {
+	return &APIError{
+		Status:  status,
+		Message: message,
+	}
+}
+
+type APIGame struct {
+	ID          string `json:"id"`
+	Season      string `json:"season"`
+	GameDate    string `json:"game_date"`
+	Matchup     string `json:"matchup"`
+	SeasonType  string `json:"season_type"`
+	WinnerID    int    `json:"winner_id"`
+	WinnerName  string `json:"winner_name"`
+	WinnerScore int    `json:"winner_score"`
+	LoserID     int    `json:"loser_id"`
+	LoserName   string `json:"loser_name"`
+	LoserScore  int    `json:"loser_score"`
+	HomeTeamID  int    `json:"home_team_id"`
+	AwayTeamID  int    `json:"away_team_id"`
+}
+
+func newAPIGame(g db.DatabaseGame) APIGame {
+	return APIGame{
+		ID:          g.ID,
+		Season:      g.Season,
+		GameDate:    g.GameDate,
+		Matchup:     g.Matchup,
+		SeasonType:  g.SeasonType,
+		WinnerID:    g.WinnerID,
+		WinnerName:  g.WinnerName,
+		WinnerScore: g.WinnerScore,
+		LoserID:     g.LoserID,
+		LoserName:   g.LoserName,
+		LoserScore:  g.LoserScore,
+		HomeTeamID:  g.HomeTeamId,
+		AwayTeamID:  g.AwayTeamId,
+	}
+}
+
+type APITeam struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+}
+
+func newAPITeam(t db.Team) APITeam {
+	return APITeam{
+		ID:           t.ID,
+		Name:         t.FullName(),
+		Abbreviation: t.Abbreviation,
+	}
+}
+
+type APIPlayer struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Teams []string `json:"teams"`
+}
+
+func newAPIPlayer(p db.PlayerSearchInfo) APIPlayer {
+	teams := []string{}
+	for _, t := range strings.Split(p.TeamAbbreviations, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			teams = append(teams, t)
+		}
+	}
+	return APIPlayer{
+		ID:    p.PlayerID,
+		Name:  p.PlayerName,
+		Teams: teams,
+	}
+}
+
+type APIMeasure struct {
+	Measure string `json:"measure"`
+	Label   string `json:"label"`
+	Default bool   `json:"default"`
+}
+
+type APIJob struct {
+	Slug           string       `json:"slug"`
+	State          string       `json:"state"`
+	Season         string       `json:"season"`
+	Games          []string     `json:"games"`
+	Players        []int        `json:"players"`
+	Measures       []string     `json:"measures"`
+	DunksOnly      bool         `json:"dunks_only"`
+	Situation      APISituation `json:"situation"`
+	Clutch         bool         `json:"clutch"`
+	Team           *int         `json:"team"`
+	ErrorDetails   *string      `json:"error_details"`
+	Progress       APIProgress  `json:"progress"`
+	Attempts       int          `json:"attempts"`
+	NextAttemptAt  *time.Time   `json:"next_attempt_at"`
+	WorkerID       *string      `json:"worker_id"`
+	LeaseExpiresAt *time.Time   `json:"lease_expires_at"`
+	Priority       int          `json:"priority"`
+	EstimatedCost  int          `json:"estimated_cost"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+type APIProgress struct {
+	Stage   string `json:"stage"`
+	Current int    `json:"current"`
+	Total   int    `json:"total"`
+	Label   string `json:"label"`
+}
+
+func newAPIJob(j *db.Job) (*APIJob, error) {
+	players := []int{}
+	for _, idString := range j.PlayerIDs() {
+		id, err := strconv.Atoi(idString)
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		players = append(players, id)
+	}
+	return &APIJob{
+		Slug:         j.Slug,
+		State:        j.State,
+		Season:       j.Season,
+		Games:        strings.Split(j.Games, ","),
+		Players:      players,
+		Measures:     j.ContextMeasures(),
+		DunksOnly:    j.DunksOnly,
+		Situation:    newAPISituation(j.AssetOptions()),
+		Clutch:       j.AssetOptions().IsClutch(),
+		Team:         j.TeamID,
+		ErrorDetails: j.ErrorDetails,
+		Progress: APIProgress{
+			Stage:   j.ProgressStage,
+			Current: j.ProgressCurrent,
+			Total:   j.ProgressTotal,
+			Label:   j.ProgressLabel(),
+		},
+		Attempts:       j.Attempts,
+		NextAttemptAt:  j.NextAttemptAt,
+		WorkerID:       j.WorkerID,
+		LeaseExpiresAt: j.LeaseExpiresAt,
+		Priority:       j.Priority,
+		EstimatedCost:  j.EstimatedCost,
+		CreatedAt:      j.CreatedAt,
+		UpdatedAt:      j.UpdatedAt,
+	}, nil
+}
+
+// Zero values leave that part of the situation open
+type APISituation struct {
+	ClutchTime  string `json:"clutch_time"`
+	AheadBehind string `json:"ahead_behind"`
+	PointDiff   int    `json:"point_diff"`
+	Period      int    `json:"period"`
+	StartPeriod int    `json:"start_period"`
+	EndPeriod   int    `json:"end_period"`
+}
+
+func newAPISituation(o nba.VideoDetailsAssetOptions) APISituation {
+	return APISituation{
+		ClutchTime:  string(o.ClutchTime),
+		AheadBehind: string(o.AheadBehind),
+		PointDiff:   o.PointDiff,
+		Period:      o.Period,
+		StartPeriod: o.StartPeriod,
+		EndPeriod:   o.EndPeriod,
+	}
+}
+
+func (s APISituation) options() nba.VideoDetailsAssetOptions {
+	return nba.VideoDetailsAssetOptions{
+		ClutchTime:  nba.ClutchTime(s.ClutchTime),
+		AheadBehind: nba.AheadBehind(s.AheadBehind),
+		PointDiff:   s.PointDiff,
+		Period:      s.Period,
+		StartPeriod: s.StartPeriod,
+		EndPeriod:   s.EndPeriod,
+	}
+}
+
+type APIVideo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+func newAPIVideo(v *db.Video) *APIVideo {
+	return &APIVideo{
+		Title:       v.Title,
+		Description: v.Description,
+		URL:         v.URL,
+	}
+}
+
+type APIClip struct {
+	Position       int     `json:"position"`
+	GameID         string  `json:"game_id"`
+	EventID        int     `json:"event_id"`
+	Period         *int    `json:"period"`
+	Description    string  `json:"description"`
+	SourceURL      string  `json:"source_url"`
+	Resolution     string  `json:"resolution"`
+	DownloadStatus string  `json:"download_status"`
+	ErrorDetails   *string `json:"error_details"`
+}
+
+func newAPIClip(c db.JobClip) APIClip {
+	return APIClip{
+		Position:       c.Position,
+		GameID:         c.GameID,
+		EventID:        c.EventID,
+		Period:         c.Period,
+		Description:    c.Description,
+		SourceURL:      c.SourceURL,
+		Resolution:     c.Resolution,
+		DownloadStatus: c.DownloadStatus,
+		ErrorDetails:   c.ErrorDetails,
+	}
+}
+
+// Measures default when the field is left out entirely
+func (r APIJobRequest) jobRequest() JobRequest {
+	playerIDs := make([]string, 0, len(r.Players))
+	for _, id := range r.Players {
+		playerIDs = append(playerIDs, strconv.Itoa(id))
+	}
+	measures := r.Measures
+	if measures == nil {
+		for _, m := range nba.DefaultContextMeasures {
+			measures = append(measures, string(m))
+		}
+	}
+	return JobRequest{
+		Season:     r.Season,
+		GameIDs:    r.Games,
+		PlayerIDs:  playerIDs,
+		Measures:   measures,
+		DunksOnly:  r.DunksOnly,
+		Situation:  r.Situation.options(),
+		Clutch:     r.Clutch,
+		DateFrom:   r.DateFrom,
+		DateTo:     r.DateTo,
+		LastNGames: r.LastNGames,
+		TeamID:     r.Team,
+	}
+}
+
+type APIEstimate struct {
+	Lookups int  `json:"lookups"`
+	Clips   int  `json:"clips"`
+	Seconds int  `json:"seconds"`
+	Known   bool `json:"known"`
+	// clips and seconds are an upper bound when the job only wants plays from a game situation
+	Narrowed bool `json:"narrowed"`
+	// why the job would be turned away, null when it's within the limits
+	OverLimit *string `json:"over_limit"`
+}
+
+// Slugs are all it takes to see or cancel a job, so which job a worker has stays out of here
+type APIWorker struct {
+	Id    int       `json:"id"`
+	Name  string    `json:"name"`
+	State string    `json:"state"`
+	Since time.Time `json:"since"`
+}
+
+func newAPIWorker(s jobs.WorkerStatus) APIWorker {
+	w := APIWorker{
+		Id:    s.Id,
+		Name:  s.Name,
+		State: "idle",
+		Since: s.Since,
+	}
+	if !s.Idle {
+		w.State = "busy"
+	}
+	return w
+}
+
+type APIJobRequest struct {
+	Season string `json:"season"`
+	// leave out for the players' whole season, or the games within the dates below
+	Games     []string `json:"games"`
+	Players   []int    `json:"players"`
+	Measures  []string `json:"measures"`
+	DunksOnly bool     `json:"dunks_only"`
+	// leave out for clips from the whole game
+	Situation APISituation `json:"situation"`
+	// clutch time plays only
+	Clutch bool `json:"clutch"`
+	// YYYY-MM-DD, only used when games are left out
+	DateFrom   string `json:"date_from"`
+	DateTo     string `json:"date_to"`
+	LastNGames int    `json:"last_n_games"`
+	// a team id in place of players, for all of the team's plays in the games
+	Team int `json:"team"`
+}
+
+// Everything under /api/v1 speaks json, errors included
+func registerAPIRoutes(e *echo.Echo) {
+	api := e.Group(apiPrefix)
+
+	api.GET("/seasons", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]any{
+			"seasons": config.ValidSeasons,
+		})
+	})
+
+	api.GET("/measures", func(c echo.Context) error {
+		measures := make([]APIMeasure, 0, len(nba.SelectableContextMeasures))
+		for _, o := range newMeasureOptions(nba.DefaultContextMeasures) {
+			measures = append(measures, APIMeasure{
+				Measure: string(o.Measure),
+				Label:   o.Label,
+				Default: o.Checked,
+			})
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"measures": measures,
+		})
+	})
+
+	api.GET("/situations", func(c echo.Context) error {
+		options := newSituationOptions()
+		return c.JSON(http.StatusOK, map[string]any{
+			"clutch_times":  options.ClutchTimes,
+			"ahead_behinds": options.AheadBehinds,
+			"max_period":    nba.MaxPeriod,
+		})
+	})
+
+	api.GET("/teams", func(c echo.Context) error {
+		teams, err := db.SelectTeams()
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		apiTeams := make([]APITeam, 0, len(teams))
+		for _, t := range teams {
+			apiTeams = append(apiTeams, newAPITeam(t))
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"teams": apiTeams,
+		})
+	})
+
+	api.GET("/seasons/:season/games", func(c echo.Context) error {
+		season := c.Param("season")
+		if utils.IsInvalidSeason(season) {
+			return newAPIError(http.StatusNotFound, fmt.Sprintf("unknown season '%s'", season))
+		}
+		games, err := db.SelectGamesBySeason(season)
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		games, err = filterGamesByQuery(games, c.QueryParam("q"))
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, "invalid search query")
+		}
+		apiGames := make([]APIGame, 0, len(games))
+		for _, g := range games {
+			apiGames = append(apiGames, newAPIGame(g))
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"games": apiGames,
+		})
+	})
+
+	api.GET("/seasons/:season/players", func(c echo.Context) error {
+		season := c.Param("season")
+		if utils.IsInvalidSeason(season) {
+			return newAPIError(http.StatusNotFound, fmt.Sprintf("unknown season '%s'", season))
+		}
+		players, err := db.GetPlayerPlayerSearchInfoBySeason(season)
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		players, err = filterPlayersByQuery(players, c.QueryParam("q"))
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, "invalid search query")
+		}
+		apiPlayers := make([]APIPlayer, 0, len(players))
+		for _, p := range players {
+			apiPlayers = append(apiPlayers, newAPIPlayer(p))
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"players": apiPlayers,
+		})
+	})
+
+	api.POST("/jobs", func(c echo.Context) error {
+		var body APIJobRequest
+		if err := c.Bind(&body); err != nil {
+			return newAPIError(http.StatusBadRequest, "malformed job request")
+		}
+
+		job, err := createJob(body.jobRequest())
+		if err != nil {
+			var userErr *UserError
+			if errors.As(err, &userErr) {
+				return newAPIError(http.StatusUnprocessableEntity, userErr.Message)
+			}
+			return utils.ErrorWithTrace(err)
+		}
+
+		apiJob, err := newAPIJob(job)
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("%s/jobs/%s", apiPrefix, job.Slug))
+		return c.JSON(http.StatusAccepted, map[string]any{
+			"job": apiJob,
+		})
+	})
+
+	api.POST("/jobs/:slug/cancel", func(c echo.Context) error {
+		slug := c.Param("slug")
+		job, err := jobs.Cancel(slug)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return newAPIError(http.StatusNotFound, fmt.Sprintf("no job found for '%s'", slug))
+			}
+			return utils.ErrorWithTrace(err)
+		}
+		if job.State != "CANCELLED" {
+			return newAPIError(http.StatusConflict, fmt.Sprintf("job '%s' already %s", slug, strings.ToLower(job.State)))
+		}
+		apiJob, err := newAPIJob(job)
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"job": apiJob,
+		})
+	})
+
+	api.POST("/estimate", func(c echo.Context) error {
+		var body APIJobRequest
+		if err := c.Bind(&body); err != nil {
+			return newAPIError(http.StatusBadRequest, "malformed job request")
+		}
+		r := body.jobRequest()
+		resolved, err := resolveJobRequest(r)
+		var userErr *UserError
+		if errors.As(err, &userErr) {
+			return newAPIError(http.StatusUnprocessableEntity, userErr.Message)
+		} else if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+
+		est, err := estimateJob(resolved)
+		if err != nil && !errors.As(err, &userErr) {
+			return utils.ErrorWithTrace(err)
+		}
+		apiEstimate := APIEstimate{
+			Lookups:  est.Lookups,
+			Clips:    est.Clips,
+			Seconds:  est.Seconds,
+			Known:    est.Known,
+			Narrowed: est.Narrowed,
+		}
+		if userErr != nil {
+			apiEstimate.OverLimit = &userErr.Message
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"estimate": apiEstimate,
+		})
+	})
+
+	api.GET("/jobs/:slug", func(c echo.Context) error {
+		slug := c.Param("slug")
+		job, err := db.SelectJobBySlug(slug)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return newAPIError(http.StatusNotFound, fmt.Sprintf("no job found for '%s'", slug))
+			}
+			return utils.ErrorWithTrace(err)
+		}
+		apiJob, err := newAPIJob(job)
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+
+		var video *APIVideo
+		if job.State == "FINISHED" {
+			v, err := db.SelectVideoByJobId(job.Id)
+			if err != nil {
+				return utils.ErrorWithTrace(err)
+			}
+			video = newAPIVideo(v)
+		}
+
+		clips, err := db.SelectJobClips(job.Id)
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		apiClips := make([]APIClip, 0, len(clips))
+		for _, c := range clips {
+			apiClips = append(apiClips, newAPIClip(c))
+		}
+
+		return c.JSON(http.StatusOK, map[string]any{
+			"job":   apiJob,
+			"video": video,
+			"clips": apiClips,
+		})
+	})
+
+	api.GET("/workers", func(c echo.Context) error {
+		statuses := dispatcher.Status()
+		workers := make([]APIWorker, 0, len(statuses))
+		for _, s := range statuses {
+			workers = append(workers, newAPIWorker(s))
+		}
+		return c.JSON(http.StatusOK, map[string]any{
+			"workers": workers,
+		})
+	})
+}
+
+// Api requests get their errors as json, everything else falls through to echo's default handler
+func httpErrorHandler(e *echo.Echo) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if !strings.HasPrefix(c.Request().URL.Path, apiPrefix+"/") {
+			e.DefaultHTTPErrorHandler(err, c)
+			return
+		}
+		if c.Response().Committed {
+			return
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				apiErr = newAPIError(httpErr.Code, strings.ToLower(http.StatusText(httpErr.Code)))
+			} else {
+				log.Println(err)
+				apiErr = newAPIError(http.StatusInternalServerError, "internal server error")
+			}
+		}
+
+		if err := c.JSON(apiErr.Status, map[string]any{"error": apiErr}); err != nil {
+			log.Println(utils.ErrorWithTrace(err))
+		}
+	}
+}
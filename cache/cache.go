@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"dunkod/db"
+	"dunkod/nba"
+	"dunkod/utils"
+)
+
+// Read-through cache in front of nba.VideoDetailsAsset, backed by the assets,
+// assets_measures and asset_lookups tables. Only whole-game lookups are cached,
+// anything narrowed down by opts goes straight to the NBA
+func VideoDetailsAsset(ctx context.Context, season, gameID, playerID string, contextMeasure nba.VideoDetailsAssetContextMeasure, opts nba.VideoDetailsAssetOptions) ([]nba.VideoDetailsAssetEntry, error) {
+	if !opts.IsZero() {
+		entries, err := nba.VideoDetailsAsset(ctx, season, gameID, playerID, contextMeasure, opts)
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		return entries, nil
+	}
+
+	pid, err := strconv.Atoi(playerID)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(fmt.Errorf("invalid player id '%s' "+utils.Sad, playerID))
+	}
+
+	cached, found, err := db.SelectCachedAssets(gameID, pid, *db.NewContextMeasure(string(contextMeasure)))
+	if err != nil {
+		// a broken cache shouldn't break the job, go ask the NBA instead
+		log.Println(utils.ErrorWithTrace(err))
+	} else if found {
+		return toEntries(cached), nil
+	}
+
+	return Refresh(ctx, season, gameID, playerID, contextMeasure)
+}
+
+// Always queries the NBA API and overwrites whatever was cached for the lookup
+func Refresh(ctx context.Context, season, gameID, playerID string, contextMeasure nba.VideoDetailsAssetContextMeasure) ([]nba.VideoDetailsAssetEntry, error) {
+	pid, err := strconv.Atoi(playerID)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(fmt.Errorf("invalid player id '%s' "+utils.Sad, playerID))
+	}
+
+	entries, err := nba.VideoDetailsAsset(ctx, season, gameID, playerID, contextMeasure, nba.VideoDetailsAssetOptions{})
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+
+	// the rescrape counts on this to know the lookup is cached, so failing to
+	// store it is a failure
+	if err := db.InsertVideoAssets(gameID, pid, *db.NewContextMeasure(string(contextMeasure)), toAssets(gameID, entries)); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return entries, nil
+}
+
+func toAssets(gameID string, entries []nba.VideoDetailsAssetEntry) []db.Asset {
+	assets := make([]db.Asset, 0, len(entries))
+	for _, e := range entries {
+		if e.EventID == nil {
+			continue
+		}
+		description := ""
+		if e.Description != nil {
+			description = *e.Description
+		}
+		var year *int
+		if e.Year != nil {
+			y := int(*e.Year)
+			year = &y
+		}
+		var period *int
+		if e.Period != nil {
+			p := int(*e.Period)
+			period = &p
+		}
+		asset := db.NewAsset(
+			gameID,
+			int(*e.EventID),
+			description,
+			e.Uuid,
+			e.LargeUrl,
+			e.MedUrl,
+			e.SmallUrl,
+			year,
+			e.Month,
+			e.Day,
+			period,
+			e.IsDunk,
+		)
+		assets = append(assets, *asset)
+	}
+	return assets
+}
+
+func toEntries(assets []db.Asset) []nba.VideoDetailsAssetEntry {
+	entries := make([]nba.VideoDetailsAssetEntry, 0, len(assets))
+	for _, a := range assets {
+		gameID := a.GameID
+		eventID := float64(a.EventID)
+		description := a.Description
+		var year *float64
+		if a.Year != nil {
+			y := float64(*a.Year)
+			year = &y
+		}
+		var period *float64
+		if a.Period != nil {
+			p := float64(*a.Period)
+			period = &p
+		}
+		entries = append(entries, nba.VideoDetailsAssetEntry{
+			GameID:      &gameID,
+			EventID:     &eventID,
+			Year:        year,
+			Month:       a.Month,
+			Day:         a.Day,
+			Period:      period,
+			Description: &description,
+			Uuid:        a.Uuid,
+			LargeUrl:    a.LargeURL,
+			MedUrl:      a.MedURL,
+			SmallUrl:    a.SmallURL,
+			IsDunk:      a.IsDunk,
+		})
+	}
+	return entries
+}
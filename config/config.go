@@ -2,16 +2,43 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
+	"time"
 )
 
 var DatabaseFile string
 var SecretFile string
 var TokenFile string
+var VideosDir string
+
+// Key for signing session cookies, made on first start if it doesn't exist
+var SessionKeyFile string
+
+// Where jobs keep their clips and reel between attempts, one directory per job hash
+var WorkDir string
+
 var ProdFlag *bool
 var BigScrape *bool
+var PublisherName *string
+
+// Fraction of a job's clips that have to download for the reel to go ahead without the rest
+var ClipThreshold *float64
+
+// How many jobs run at once
+var Workers *int
+
+// How long jobs in flight get to finish on shutdown before they're put back in the queue
+var ShutdownTimeout *time.Duration
+
+// How long a worker holds a job without renewing before another worker may take it over
+var JobLease *time.Duration
+
+// Biggest job anyone can ask for, in asset lookups (games x players x measures) and estimated clips
+var MaxLookups *int
+var MaxClips *int
 
 // Sorted slice of all valid seasons
 //
@@ -75,7 +102,27 @@ var TeamIDs = []int{
 func LoadConfig() error {
 	ProdFlag = flag.Bool("p", false, "designates production")
 	BigScrape = flag.Bool("s", false, "do big scrape task and then die")
+	PublisherName = flag.String("publisher", "youtube", "where finished videos go: youtube or local")
+	ClipThreshold = flag.Float64("clip-threshold", 1, "fraction of clips that must download for a job to skip the rest, 1 requires every clip")
+	Workers = flag.Int("workers", 4, "number of jobs to run at once")
+	ShutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second, "how long to wait for running jobs on shutdown before requeueing them")
+	JobLease = flag.Duration("lease", time.Minute, "how long a job stays claimed by a worker that stopped renewing it")
+	MaxLookups = flag.Int("max-lookups", 200, "most asset lookups (games x players x plays) a single job may need")
+	MaxClips = flag.Int("max-clips", 400, "most clips a single job is estimated to have")
 	flag.Parse()
+	if *ClipThreshold <= 0 || *ClipThreshold > 1 {
+		return fmt.Errorf("clip-threshold must be greater than 0 and at most 1, got %v", *ClipThreshold)
+	}
+	if *Workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", *Workers)
+	}
+	if *MaxLookups < 1 || *MaxClips < 1 {
+		return fmt.Errorf("max-lookups and max-clips must be at least 1, got %d and %d", *MaxLookups, *MaxClips)
+	}
+	// the lease is renewed every third of it and stored to the second
+	if *JobLease < 3*time.Second {
+		return fmt.Errorf("lease must be at least 3s, got %s", *JobLease)
+	}
 	binPath, err := os.Executable()
 	if err != nil {
 		return err
@@ -85,10 +132,16 @@ func LoadConfig() error {
 		DatabaseFile = "/sqlitedata/database.db"
 		SecretFile = "/secrets/secret.json"
 		TokenFile = "/secrets/token.json"
+		SessionKeyFile = "/secrets/session.key"
+		VideosDir = "/videos"
+		WorkDir = "/work"
 	} else {
 		DatabaseFile = filepath.Join(filepath.Dir(binPath), "database.db")
 		SecretFile = filepath.Join(filepath.Dir(binPath), "secret.json")
 		TokenFile = filepath.Join(filepath.Dir(binPath), "token.json")
+		SessionKeyFile = filepath.Join(filepath.Dir(binPath), "session.key")
+		VideosDir = filepath.Join(filepath.Dir(binPath), "videos")
+		WorkDir = filepath.Join(filepath.Dir(binPath), "work")
 	}
 	slices.Sort(ValidSeasons)
 	slices.Reverse(ValidSeasons)
@@ -13,6 +13,7 @@ import (
 
 	"database/sql"
 	"dunkod/config"
+	"dunkod/nba"
 	"dunkod/utils"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -134,10 +135,9 @@ func validateDbRO(db *sqlx.DB) error {
 	return nil
 }
 
+// Migrations are applied incrementally from the version recorded in schema_migrations.
+// Later migrations ALTER existing tables, so they can't be blindly re-run on every boot.
 func RunMigrations() error {
-	if _, err := dbRW.Exec("DROP TABLE IF EXISTS schema_migrations;"); err != nil {
-		return utils.ErrorWithTrace(err)
-	}
 	m, err := migrate.New(
 		"file://db/migrations",
 		"sqlite3://"+config.DatabaseFile,
@@ -503,6 +503,9 @@ func SelectPlayersWhoPlayedBySeason(season string, timeout ...time.Duration) ([]
 }
 
 func SelectPlayerNamesById(ids []string, timeout ...time.Duration) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
@@ -531,6 +534,35 @@ func SelectPlayerNamesById(ids []string, timeout ...time.Duration) ([]string, er
 	return names, nil
 }
 
+func SelectPlayersById(ids []string, timeout ...time.Duration) ([]Player, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := sqlx.In("SELECT * FROM players WHERE id IN (?);", ids)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	query = tx.Rebind(query)
+	players := []Player{}
+	if err := selekt(tx, &ctx, &players, query, args...); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return players, nil
+}
+
 type BoxScorePlayerStat struct {
 	Id        int       `db:"id"`
 	PlayerID  int       `db:"player_id"`
@@ -721,53 +753,11 @@ func InsertBoxScorePlayerStats(stats []BoxScorePlayerStat, timeout ...time.Durat
 	return scrapingErrors, nil
 }
 
-type Job struct {
-	Id           int       `db:"id"`
-	Players      string    `db:"players"`
-	Games        string    `db:"games"`
-	Season       string    `db:"season"`
-	Slug         string    `db:"slug"`
-	State        string    `db:"job_state"`
-	Hash         string    `db:"job_hash"`
-	ErrorDetails *string   `db:"error_details"`
-	CreatedAt    time.Time `db:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at"`
-}
-
-func NewJob(playerIds, gameIds []string, season string) *Job {
-	slices.Sort(playerIds)
-	slices.Sort(gameIds)
-	hashString := strings.Join(playerIds, ",") + "|" + strings.Join(gameIds, ",") + "|" + season
-	jobHash := fmt.Sprintf("%x", sha1.Sum([]byte(hashString)))
-	gameIdsCSV := strings.Join(gameIds, ",")
-	playerIdsCSV := strings.Join(playerIds, ",")
-
-	return &Job{
-		State:   "PENDING",
-		Games:   gameIdsCSV,
-		Players: playerIdsCSV,
-		Season:  season,
-		Hash:    jobHash,
+// Every player that actually got on the floor in the provided games
+func SelectBoxScorePlayerStatsWithMinutes(gameIDs []string, timeout ...time.Duration) ([]BoxScorePlayerStat, error) {
+	if len(gameIDs) == 0 {
+		return []BoxScorePlayerStat{}, nil
 	}
-}
-
-func (j *Job) GamesIDs() []string {
-	return strings.Split(j.Games, ",")
-}
-
-func (j *Job) PlayerIDs() []string {
-	return strings.Split(j.Players, ",")
-}
-
-func (j *Job) OhNo(e error) error {
-	log.Println(e.Error())
-	j.State = "ERROR"
-	errorDetails := e.Error()
-	j.ErrorDetails = &errorDetails
-	return UpdateJob(j)
-}
-
-func InsertJob(job *Job, timeout ...time.Duration) (*Job, error) {
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
@@ -775,66 +765,124 @@ func InsertJob(job *Job, timeout ...time.Duration) (*Job, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
 	defer cancel()
 
-	tx, err := dbRW.Beginx()
+	tx, err := dbRO.Beginx()
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
 	defer tx.Rollback()
 
-	var existingJob Job
-	err = get(tx, &ctx, &existingJob, "SELECT * FROM jobs WHERE job_hash = ?;", job.Hash)
-	if err == nil {
-		return &existingJob, nil
-	} else if !strings.Contains(err.Error(), sql.ErrNoRows.Error()) {
+	query := `
+		SELECT	*
+		FROM	box_score_player_stats
+		WHERE	game_id IN (?)
+			AND dnp = FALSE
+			AND min IS NOT NULL
+			AND min != '';
+	`
+	query, args, err := sqlx.In(query, gameIDs)
+	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-
-	maxAttempts := 50
-	var slug string
-	var count int
-	slugStmt, err := tx.Prepare("SELECT COUNT(*) FROM jobs WHERE slug = ?;")
-	if err != nil {
+	query = tx.Rebind(query)
+	stats := []BoxScorePlayerStat{}
+	if err := selekt(tx, &ctx, &stats, query, args...); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	for range maxAttempts {
-		slug = utils.CreateSlug()
-		err := stmtDotQueryRow(slugStmt, &ctx, []any{slug}, []any{&count})
-		if err != nil {
-			return nil, utils.ErrorWithTrace(err)
-		}
-		if count == 0 {
-			break
-		}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
 	}
-	if count != 0 {
-		return nil, utils.ErrorWithTrace(fmt.Errorf("failed to create unique slug after %d attempts", maxAttempts))
+	return stats, nil
+}
+
+// Box score totals over a set of games and players, one field per stat that
+// a selectable context measure can be estimated from
+type BoxScoreTotals struct {
+	// player games the totals were summed over
+	PlayerGames int     `db:"player_games"`
+	FGM         float64 `db:"fgm"`
+	FGA         float64 `db:"fga"`
+	FG3M        float64 `db:"fg3m"`
+	FG3A        float64 `db:"fg3a"`
+	FTM         float64 `db:"ftm"`
+	OREB        float64 `db:"oreb"`
+	DREB        float64 `db:"dreb"`
+	REB         float64 `db:"reb"`
+	AST         float64 `db:"ast"`
+	STL         float64 `db:"stl"`
+	BLK         float64 `db:"blk"`
+	TOV         float64 `db:"tov"`
+	PF          float64 `db:"pf"`
+}
+
+func SelectBoxScoreTotals(gameIDs, playerIDs []string, timeout ...time.Duration) (*BoxScoreTotals, error) {
+	totals := &BoxScoreTotals{}
+	if len(gameIDs) == 0 || len(playerIDs) == 0 {
+		return totals, nil
 	}
-	job.Slug = slug
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
 
 	query := `
-		INSERT OR IGNORE INTO jobs (
-			players, games, season, slug, job_state, job_hash
-		) VALUES (
-			:players, :games, :season, :slug, :job_state, :job_hash
-		);
+		SELECT	COUNT(*) AS player_games,
+				COALESCE(SUM(fgm), 0) AS fgm,
+				COALESCE(SUM(fga), 0) AS fga,
+				COALESCE(SUM(fg3m), 0) AS fg3m,
+				COALESCE(SUM(fg3a), 0) AS fg3a,
+				COALESCE(SUM(ftm), 0) AS ftm,
+				COALESCE(SUM(oreb), 0) AS oreb,
+				COALESCE(SUM(dreb), 0) AS dreb,
+				COALESCE(SUM(reb), 0) AS reb,
+				COALESCE(SUM(ast), 0) AS ast,
+				COALESCE(SUM(stl), 0) AS stl,
+				COALESCE(SUM(blk), 0) AS blk,
+				COALESCE(SUM(tov), 0) AS tov,
+				COALESCE(SUM(pf), 0) AS pf
+		FROM	box_score_player_stats
+		WHERE	game_id IN (?)
+			AND player_id IN (?)
+			AND dnp = FALSE;
 	`
-	if err := namedExec(tx, &ctx, query, job); err != nil {
+	query, args, err := sqlx.In(query, gameIDs, playerIDs)
+	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	if err := commitTx(tx, &ctx); err != nil {
-		return nil, err
-	}
-	tx, err = dbRO.Beginx()
-	if err != nil {
+	query = tx.Rebind(query)
+	if err := get(tx, &ctx, totals, query, args...); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	if err := get(tx, &ctx, job, "SELECT * FROM jobs WHERE job_hash = ?;", job.Hash); err != nil {
+	if err := commitTx(tx, &ctx); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	return job, nil
+	return totals, nil
 }
 
-func SelectJobBySlug(slug string, timeout ...time.Duration) (*Job, error) {
+// Games any of the players got minutes in during the season, oldest first.
+// Blank dates leave that end of the range open and lastN keeps only the most
+// recent games, 0 keeps them all
+func SelectPlayedGameIDs(season string, playerIDs []string, dateFrom, dateTo string, lastN int, timeout ...time.Duration) ([]string, error) {
+	if len(playerIDs) == 0 {
+		return []string{}, nil
+	}
+	return selectPlayedGameIDs(season, "b.player_id", playerIDs, dateFrom, dateTo, lastN, timeout...)
+}
+
+// Same as SelectPlayedGameIDs for the games the team played
+func SelectTeamGameIDs(season string, teamID int, dateFrom, dateTo string, lastN int, timeout ...time.Duration) ([]string, error) {
+	return selectPlayedGameIDs(season, "b.team_id", []int{teamID}, dateFrom, dateTo, lastN, timeout...)
+}
+
+// column is trusted, ids is a slice for its IN list
+func selectPlayedGameIDs(season, column string, ids any, dateFrom, dateTo string, lastN int, timeout ...time.Duration) ([]string, error) {
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
@@ -848,17 +896,45 @@ func SelectJobBySlug(slug string, timeout ...time.Duration) (*Job, error) {
 	}
 	defer tx.Rollback()
 
-	var job Job
-	if err := get(tx, &ctx, &job, "SELECT * from jobs where slug = ?;", slug); err != nil {
+	query := `
+		SELECT	game_id
+		FROM	(
+					SELECT	b.game_id,
+							MIN(g.game_date) AS game_date
+					FROM	box_score_player_stats b
+							INNER JOIN games g ON g.id = b.game_id
+					WHERE	b.season = ?
+						AND ` + column + ` IN (?)
+						AND b.dnp = FALSE
+						AND (? = '' OR g.game_date >= ?)
+						AND (? = '' OR g.game_date <= ?)
+					GROUP BY b.game_id
+					ORDER BY game_date DESC, b.game_id DESC
+					LIMIT CASE WHEN ? > 0 THEN ? ELSE -1 END
+				)
+		ORDER BY game_date, game_id;
+	`
+	query, args, err := sqlx.In(query, season, ids, dateFrom, dateFrom, dateTo, dateTo, lastN, lastN)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	query = tx.Rebind(query)
+	gameIDs := []string{}
+	if err := selekt(tx, &ctx, &gameIDs, query, args...); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
 	if err := commitTx(tx, &ctx); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	return &job, nil
+	return gameIDs, nil
 }
 
-func SelectJobForUpdate(timeout ...time.Duration) (*Job, error) {
+// Everyone who got minutes for the team in any of the games
+func SelectTeamPlayerIDs(teamID int, gameIDs []string, timeout ...time.Duration) ([]string, error) {
+	playerIDs := []string{}
+	if len(gameIDs) == 0 {
+		return playerIDs, nil
+	}
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
@@ -866,31 +942,121 @@ func SelectJobForUpdate(timeout ...time.Duration) (*Job, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
 	defer cancel()
 
-	tx, err := dbRW.Beginx()
+	tx, err := dbRO.Beginx()
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
 	defer tx.Rollback()
 
-	var job Job
-	if err := get(tx, &ctx, &job, "SELECT * FROM jobs WHERE job_state = 'PENDING' ORDER BY created_at LIMIT 1;"); err != nil {
-		if strings.Contains(err.Error(), sql.ErrNoRows.Error()) {
-			return nil, fmt.Errorf("QUEUE EMPTY")
-		} else {
-			return nil, utils.ErrorWithTrace(err)
-		}
+	query := `
+		SELECT	DISTINCT CAST(player_id AS TEXT)
+		FROM	box_score_player_stats
+		WHERE	team_id = ?
+			AND game_id IN (?)
+			AND dnp = FALSE
+		ORDER BY player_id;
+	`
+	query, args, err := sqlx.In(query, teamID, gameIDs)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
 	}
-	job.State = "PROCESSING"
-	if err := exec(tx, &ctx, "UPDATE jobs SET job_state = 'PROCESSING' WHERE id = ?;", job.Id); err != nil {
+	query = tx.Rebind(query)
+	if err := selekt(tx, &ctx, &playerIDs, query, args...); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
 	if err := commitTx(tx, &ctx); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	return &job, nil
-}
-
-func UpdateJob(job *Job, timeout ...time.Duration) error {
+	return playerIDs, nil
+}
+
+// One event from a game's play-by-play. The NBA credits a single player per
+// event, whoever assisted, stole or blocked is worked out from the description
+type PlayByPlayEvent struct {
+	Id           int    `db:"id"`
+	GameID       string `db:"game_id"`
+	ActionNumber int    `db:"action_number"`
+	ActionID     *int   `db:"action_id"`
+	Period       int    `db:"period"`
+	// as the NBA sends it, i.e. "PT11M42.00S"
+	Clock            string  `db:"clock"`
+	SecondsRemaining float64 `db:"seconds_remaining"`
+	TeamID           *int    `db:"team_id"`
+	PersonID         *int    `db:"person_id"`
+	PlayerName       *string `db:"player_name"`
+	AssistPersonID   *int    `db:"assist_person_id"`
+	StealPersonID    *int    `db:"steal_person_id"`
+	BlockPersonID    *int    `db:"block_person_id"`
+	ActionType       string  `db:"action_type"`
+	SubType          *string `db:"sub_type"`
+	Description      *string `db:"play_description"`
+	ShotDistance     *int    `db:"shot_distance"`
+	ShotResult       *string `db:"shot_result"`
+	ShotValue        *int    `db:"shot_value"`
+	IsFieldGoal      bool    `db:"is_field_goal"`
+	X                *int    `db:"x"`
+	Y                *int    `db:"y"`
+	Location         *string `db:"location"`
+	// score after the event
+	ScoreHome      int       `db:"score_home"`
+	ScoreAway      int       `db:"score_away"`
+	VideoAvailable bool      `db:"video_available"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+func NewPlayByPlayEvent(
+	gameID string,
+	actionNumber int,
+	actionID *int,
+	period int,
+	clock string,
+	secondsRemaining float64,
+	teamID, personID *int,
+	playerName *string,
+	assistPersonID, stealPersonID, blockPersonID *int,
+	actionType string,
+	subType, description *string,
+	shotDistance *int,
+	shotResult *string,
+	shotValue *int,
+	isFieldGoal bool,
+	x, y *int,
+	location *string,
+	scoreHome, scoreAway int,
+	videoAvailable bool,
+) *PlayByPlayEvent {
+	return &PlayByPlayEvent{
+		GameID:           gameID,
+		ActionNumber:     actionNumber,
+		ActionID:         actionID,
+		Period:           period,
+		Clock:            clock,
+		SecondsRemaining: secondsRemaining,
+		TeamID:           teamID,
+		PersonID:         personID,
+		PlayerName:       playerName,
+		AssistPersonID:   assistPersonID,
+		StealPersonID:    stealPersonID,
+		BlockPersonID:    blockPersonID,
+		ActionType:       actionType,
+		SubType:          subType,
+		Description:      description,
+		ShotDistance:     shotDistance,
+		ShotResult:       shotResult,
+		ShotValue:        shotValue,
+		IsFieldGoal:      isFieldGoal,
+		X:                x,
+		Y:                y,
+		Location:         location,
+		ScoreHome:        scoreHome,
+		ScoreAway:        scoreAway,
+		VideoAvailable:   videoAvailable,
+	}
+}
+
+// Stores a game's play-by-play, replacing whatever was stored for it before
+func InsertPlayByPlay(gameID string, events []PlayByPlayEvent, timeout ...time.Duration) error {
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return utils.ErrorWithTrace(err)
@@ -903,109 +1069,108 @@ func UpdateJob(job *Job, timeout ...time.Duration) error {
 		return utils.ErrorWithTrace(err)
 	}
 	defer tx.Rollback()
-	query := "UPDATE jobs SET (job_state, error_details) = (:job_state, :error_details) WHERE id = :id;"
-	if err := namedExec(tx, &ctx, query, job); err != nil {
+
+	if err := exec(tx, &ctx, "DELETE FROM play_by_play WHERE game_id = ?;", gameID); err != nil {
 		return utils.ErrorWithTrace(err)
 	}
+	if len(events) > 0 {
+		query := `
+			INSERT INTO play_by_play (
+				game_id, action_number, action_id, period, clock, seconds_remaining,
+				team_id, person_id, player_name, assist_person_id, steal_person_id, block_person_id,
+				action_type, sub_type, play_description,
+				shot_distance, shot_result, shot_value, is_field_goal, x, y, location,
+				score_home, score_away, video_available
+			) VALUES (
+				:game_id, :action_number, :action_id, :period, :clock, :seconds_remaining,
+				:team_id, :person_id, :player_name, :assist_person_id, :steal_person_id, :block_person_id,
+				:action_type, :sub_type, :play_description,
+				:shot_distance, :shot_result, :shot_value, :is_field_goal, :x, :y, :location,
+				:score_home, :score_away, :video_available
+			)
+		`
+		batchSize := 500
+		if err := batchInsert(tx, &ctx, batchSize, query, events); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+	}
 	if err := commitTx(tx, &ctx); err != nil {
 		return utils.ErrorWithTrace(err)
 	}
 	return nil
 }
 
-func ResetStaleJobs(timeout ...time.Duration) error {
+// A game's play-by-play in the order it happened
+func SelectPlayByPlay(gameID string, timeout ...time.Duration) ([]PlayByPlayEvent, error) {
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
-		return utils.ErrorWithTrace(err)
+		return nil, utils.ErrorWithTrace(err)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
 	defer cancel()
-	tx, err := dbRW.Beginx()
+
+	tx, err := dbRO.Beginx()
 	if err != nil {
-		return utils.ErrorWithTrace(err)
+		return nil, utils.ErrorWithTrace(err)
 	}
 	defer tx.Rollback()
 
-	query := `
-		UPDATE jobs
-		SET job_state = CASE
-				WHEN job_state = 'PROCESSING'
-					AND Datetime(updated_at) < Datetime('now', '-5 minutes') THEN
-					'PENDING'
-				WHEN job_state = 'DOWNLOADING CLIPS'
-					AND Datetime(updated_at) < Datetime('now', '-10 minutes') THEN
-					'PENDING'
-				WHEN job_state = 'UPLOADING'
-					AND Datetime(updated_at) < Datetime('now', '-60 minutes') THEN
-					'PENDING'
-				ELSE
-					job_state
-				END
-		WHERE job_state IN ( 'PROCESSING', 'DOWNLOADING CLIPS', 'UPLOADING' );
-	`
-	if err := exec(tx, &ctx, query); err != nil {
-		return utils.ErrorWithTrace(err)
+	events := []PlayByPlayEvent{}
+	if err := selekt(tx, &ctx, &events, "SELECT * FROM play_by_play WHERE game_id = ? ORDER BY action_number;", gameID); err != nil {
+		return nil, utils.ErrorWithTrace(err)
 	}
 	if err := commitTx(tx, &ctx); err != nil {
-		return utils.ErrorWithTrace(err)
-	}
-	return nil
-}
-
-type Video struct {
-	Id          int       `db:"id"`
-	Title       string    `db:"title"`
-	Description string    `db:"video_description"`
-	YoutubeUrl  string    `db:"youtube_url"`
-	JobId       int       `db:"job_id"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
-}
-
-func NewVideo(title, description, url string, jobId int) *Video {
-	return &Video{
-		Title:       title,
-		Description: description,
-		YoutubeUrl:  url,
-		JobId:       jobId,
+		return nil, utils.ErrorWithTrace(err)
 	}
+	return events, nil
 }
 
-func InsertVideo(video *Video, timeout ...time.Duration) error {
+// The play-by-play event behind each of the job's clips, in reel order. Clips
+// whose game hasn't had its play-by-play scraped yet are left out
+func SelectJobClipPlays(jobID int, timeout ...time.Duration) ([]PlayByPlayEvent, error) {
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
-		return utils.ErrorWithTrace(err)
+		return nil, utils.ErrorWithTrace(err)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
 	defer cancel()
 
-	tx, err := dbRW.Beginx()
+	tx, err := dbRO.Beginx()
 	if err != nil {
-		return utils.ErrorWithTrace(err)
+		return nil, utils.ErrorWithTrace(err)
 	}
 	defer tx.Rollback()
 
 	query := `
-		INSERT OR IGNORE INTO videos (
-			title, video_description, youtube_url, job_id
-		) VALUES (
-			:title, :video_description, :youtube_url, :job_id
-		);
+		SELECT
+			pbp.*
+		FROM
+			job_clips jc
+			INNER JOIN play_by_play pbp ON pbp.game_id = jc.game_id
+			AND pbp.action_number = jc.event_id
+		WHERE
+			jc.job_id = ?
+		ORDER BY
+			jc.position;
 	`
-	if err := namedExec(tx, &ctx, query, video); err != nil {
-		return utils.ErrorWithTrace(err)
+	events := []PlayByPlayEvent{}
+	if err := selekt(tx, &ctx, &events, query, jobID); err != nil {
+		return nil, utils.ErrorWithTrace(err)
 	}
 	if err := commitTx(tx, &ctx); err != nil {
-		return utils.ErrorWithTrace(err)
+		return nil, utils.ErrorWithTrace(err)
 	}
-	return nil
+	return events, nil
 }
 
-func SelectVideoByJobId(id int, timeout ...time.Duration) (*Video, error) {
+func SelectGamesWithoutPlayByPlay(season string, timeout ...time.Duration) ([]DatabaseGame, error) {
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
+	if utils.IsInvalidSeason(season) {
+		return nil, fmt.Errorf("invalid season provided: %s", season)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
 	defer cancel()
 
@@ -1014,45 +1179,1007 @@ func SelectVideoByJobId(id int, timeout ...time.Duration) (*Video, error) {
 		return nil, utils.ErrorWithTrace(err)
 	}
 	defer tx.Rollback()
-	res := Video{}
-	if err := get(tx, &ctx, &res, "SELECT * FROM videos WHERE job_id = ?;", id); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, utils.ErrorWithTrace(fmt.Errorf("unable to find a video associated with this job " + utils.Sad))
-		} else {
-			return nil, utils.ErrorWithTrace(err)
-		}
+
+	query := `
+		SELECT
+			*
+		FROM
+			games g
+		WHERE
+			g.season = ?
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					play_by_play pbp
+				WHERE
+					pbp.game_id = g.id
+			)
+		ORDER BY
+			g.game_date DESC;
+	`
+	games := []DatabaseGame{}
+	if err := selekt(tx, &ctx, &games, query, season); err != nil {
+		return nil, utils.ErrorWithTrace(err)
 	}
 	if err := commitTx(tx, &ctx); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	return &res, nil
+	return games, nil
 }
 
-type BoxScoreScrapingError struct {
-	Id           int       `db:"id"`
-	GameID       string    `db:"game_id"`
-	ErrorDetails string    `db:"error_details"`
-	ErrorStatus  string    `db:"error_status"`
-	CreatedAt    time.Time `db:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at"`
-}
+type Job struct {
+	Id        int    `db:"id"`
+	Players   string `db:"players"`
+	Games     string `db:"games"`
+	Season    string `db:"season"`
+	Measures  string `db:"measures"`
+	DunksOnly bool   `db:"dunks_only"`
+	// game situation the clips are narrowed down to, see AssetOptions
+	ClutchTime  string `db:"clutch_time"`
+	AheadBehind string `db:"ahead_behind"`
+	PointDiff   int    `db:"point_diff"`
+	Period      int    `db:"period"`
+	StartPeriod int    `db:"start_period"`
+	EndPeriod   int    `db:"end_period"`
+	// when set the games are looked up by date instead of one by one, see Window
+	DateFrom string `db:"date_from"`
+	DateTo   string `db:"date_to"`
+	// set for a team's reel, the team is then looked up as a whole and there are no players
+	TeamID       *int    `db:"team_id"`
+	Slug         string  `db:"slug"`
+	State        string  `db:"job_state"`
+	Hash         string  `db:"job_hash"`
+	ErrorDetails *string `db:"error_details"`
+	// how far along the current stage of work the job is, see the Progress* stages
+	ProgressStage   string `db:"progress_stage"`
+	ProgressCurrent int    `db:"progress_current"`
+	ProgressTotal   int    `db:"progress_total"`
+	// number of times a worker has picked the job up
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt *time.Time `db:"next_attempt_at"`
+	// the worker holding the job, which it keeps for as long as it renews the lease
+	WorkerID       *string    `db:"worker_id"`
+	LeaseExpiresAt *time.Time `db:"lease_expires_at"`
+	// jobs with a higher priority always go first, it's 0 unless someone bumps it by hand
+	Priority int `db:"priority"`
+	// asset lookups skip the cache, for a NO CLIPS job that's asked for again
+	RefreshAssets bool `db:"refresh_assets"`
+	// roughly how much work the job is, see ComputeCost
+	EstimatedCost int       `db:"estimated_cost"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+const (
+	ProgressAssetLookups    = "asset lookups"
+	ProgressClipsDownloaded = "clips downloaded"
+	ProgressConcat          = "concat"
+	// tracked as a percentage
+	ProgressUpload = "upload"
+)
 
-func NewBoxScoreScrapingError(gameID string, err error) *BoxScoreScrapingError {
-	return &BoxScoreScrapingError{
-		GameID:       gameID,
-		ErrorDetails: err.Error(),
-		ErrorStatus:  "PENDING",
+func NewJob(playerIds, gameIds []string, season string, measures []string) *Job {
+	slices.Sort(playerIds)
+	slices.Sort(gameIds)
+	slices.Sort(measures)
+	gameIdsCSV := strings.Join(gameIds, ",")
+	playerIdsCSV := strings.Join(playerIds, ",")
+	measuresCSV := strings.Join(measures, ",")
+
+	job := &Job{
+		State:    "PENDING",
+		Games:    gameIdsCSV,
+		Players:  playerIdsCSV,
+		Season:   season,
+		Measures: measuresCSV,
 	}
+	job.Hash = job.ComputeHash()
+	return job
 }
 
-func InsertBoxScoreScrapingErrors(errors []BoxScoreScrapingError, timeout ...time.Duration) error {
-	parsedTimeout, err := parseTimeout(timeout...)
-	if err != nil {
-		return utils.ErrorWithTrace(err)
+// Two jobs with the same hash produce the same video, so anything that changes
+// which clips end up in the reel needs to be folded in here.
+func (j *Job) ComputeHash() string {
+	hashString := j.Players + "|" + j.Games + "|" + j.Season + "|" + j.Measures
+	// optional modes are only appended when set so they don't shift the hash of every other job
+	if j.DunksOnly {
+		hashString += "|dunks-only"
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
-	defer cancel()
+	if key := j.AssetOptions().Key(); key != "" {
+		hashString += "|" + key
+	}
+	if key := j.Window().Key(); key != "" {
+		hashString += "|" + key
+	}
+	if j.TeamID != nil {
+		hashString += fmt.Sprintf("|team=%d", *j.TeamID)
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(hashString)))
+}
+
+func (j *Job) AssetOptions() nba.VideoDetailsAssetOptions {
+	return nba.VideoDetailsAssetOptions{
+		ClutchTime:  nba.ClutchTime(j.ClutchTime),
+		AheadBehind: nba.AheadBehind(j.AheadBehind),
+		PointDiff:   j.PointDiff,
+		Period:      j.Period,
+		StartPeriod: j.StartPeriod,
+		EndPeriod:   j.EndPeriod,
+	}
+}
+
+func (j *Job) SetAssetOptions(opts nba.VideoDetailsAssetOptions) {
+	j.ClutchTime = string(opts.ClutchTime)
+	j.AheadBehind = string(opts.AheadBehind)
+	j.PointDiff = opts.PointDiff
+	j.Period = opts.Period
+	j.StartPeriod = opts.StartPeriod
+	j.EndPeriod = opts.EndPeriod
+}
+
+func (j *Job) Window() nba.VideoDetailsAssetWindow {
+	return nba.VideoDetailsAssetWindow{
+		DateFrom: j.DateFrom,
+		DateTo:   j.DateTo,
+	}
+}
+
+func (j *Job) SetWindow(window nba.VideoDetailsAssetWindow) {
+	j.DateFrom = window.DateFrom
+	j.DateTo = window.DateTo
+}
+
+// The number of asset lookups the job takes, which is what most of a job's
+// time and load on the NBA api comes down to
+func (j *Job) ComputeCost() int {
+	players := len(j.PlayerIDs())
+	if j.TeamID != nil {
+		players = 1
+	}
+	return LookupCount(j.GamesIDs(), players, len(j.ContextMeasures()), j.Window())
+}
+
+// Asset lookups are one per game, player and measure, unless the games are
+// picked by a window, then it's one per season type, player and measure. A
+// team counts as a single player
+func LookupCount(gameIDs []string, players, measures int, window nba.VideoDetailsAssetWindow) int {
+	games := len(gameIDs)
+	if !window.IsZero() {
+		seasonTypes, err := nba.GameIDsToSeasonTypeStrings(gameIDs)
+		if err != nil {
+			log.Println(utils.ErrorWithTrace(err))
+		}
+		games = len(seasonTypes)
+	}
+	return games * players * measures
+}
+
+func (j *Job) GamesIDs() []string {
+	return strings.Split(j.Games, ",")
+}
+
+func (j *Job) PlayerIDs() []string {
+	// a team's reel doesn't have any
+	if j.Players == "" {
+		return []string{}
+	}
+	return strings.Split(j.Players, ",")
+}
+
+func (j *Job) ContextMeasures() []string {
+	return strings.Split(j.Measures, ",")
+}
+
+func (j *Job) ProgressPercent() int {
+	if j.ProgressTotal <= 0 {
+		return 0
+	}
+	return min(100, j.ProgressCurrent*100/j.ProgressTotal)
+}
+
+func (j *Job) ProgressLabel() string {
+	switch j.ProgressStage {
+	case "":
+		return ""
+	case ProgressUpload:
+		return fmt.Sprintf("upload %d%%", j.ProgressPercent())
+	case ProgressConcat:
+		if j.ProgressTotal > 0 && j.ProgressCurrent >= j.ProgressTotal {
+			return "concat done"
+		}
+		return "concatenating clips"
+	default:
+		return fmt.Sprintf("%s %d/%d", j.ProgressStage, j.ProgressCurrent, j.ProgressTotal)
+	}
+}
+
+// True once the job has reached a state it will never leave
+func (j *Job) IsDone() bool {
+	return j.State == "FINISHED" || j.State == "ERROR" || j.State == "CANCELLED" || j.State == "NO CLIPS"
+}
+
+func (j *Job) OhNo(e error) error {
+	log.Println(e.Error())
+	j.State = "ERROR"
+	errorDetails := e.Error()
+	j.ErrorDetails = &errorDetails
+	return UpdateJob(j)
+}
+
+func InsertJob(job *Job, timeout ...time.Duration) (*Job, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	job.Hash = job.ComputeHash()
+	var existingJob Job
+	err = get(tx, &ctx, &existingJob, "SELECT * FROM jobs WHERE job_hash = ?;", job.Hash)
+	if err == nil && existingJob.State == "NO CLIPS" {
+		// games get played and the NBA posts video after the fact, so a job that
+		// came up empty gets another go instead of staying empty for good. What it
+		// found last time is cached, so that's skipped
+		query := `
+			UPDATE jobs
+			SET
+				job_state = 'PENDING',
+				refresh_assets = TRUE,
+				error_details = NULL,
+				progress_stage = '',
+				progress_current = 0,
+				progress_total = 0,
+				attempts = 0,
+				next_attempt_at = NULL,
+				worker_id = NULL,
+				lease_expires_at = NULL
+			WHERE id = ?;
+		`
+		if err := exec(tx, &ctx, query, existingJob.Id); err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		if err := get(tx, &ctx, &existingJob, "SELECT * FROM jobs WHERE id = ?;", existingJob.Id); err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		if err := commitTx(tx, &ctx); err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		return &existingJob, nil
+	} else if err == nil {
+		return &existingJob, nil
+	} else if !strings.Contains(err.Error(), sql.ErrNoRows.Error()) {
+		return nil, utils.ErrorWithTrace(err)
+	}
+
+	maxAttempts := 50
+	var slug string
+	var count int
+	slugStmt, err := tx.Prepare("SELECT COUNT(*) FROM jobs WHERE slug = ?;")
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	for range maxAttempts {
+		slug = utils.CreateSlug()
+		err := stmtDotQueryRow(slugStmt, &ctx, []any{slug}, []any{&count})
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		if count == 0 {
+			break
+		}
+	}
+	if count != 0 {
+		return nil, utils.ErrorWithTrace(fmt.Errorf("failed to create unique slug after %d attempts", maxAttempts))
+	}
+	job.Slug = slug
+	job.EstimatedCost = job.ComputeCost()
+
+	query := `
+		INSERT OR IGNORE INTO jobs (
+			players, games, season, measures, dunks_only, clutch_time, ahead_behind, point_diff, period, start_period, end_period,
+			date_from, date_to, team_id, slug, job_state, job_hash, priority, estimated_cost
+		) VALUES (
+			:players, :games, :season, :measures, :dunks_only, :clutch_time, :ahead_behind, :point_diff, :period, :start_period, :end_period,
+			:date_from, :date_to, :team_id, :slug, :job_state, :job_hash, :priority, :estimated_cost
+		);
+	`
+	if err := namedExec(tx, &ctx, query, job); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, err
+	}
+	tx, err = dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := get(tx, &ctx, job, "SELECT * FROM jobs WHERE job_hash = ?;", job.Hash); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return job, nil
+}
+
+func SelectJobBySlug(slug string, timeout ...time.Duration) (*Job, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	var job Job
+	if err := get(tx, &ctx, &job, "SELECT * from jobs where slug = ?;", slug); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &job, nil
+}
+
+// How much a waiting job's cost drops for every minute it's been waiting, so big
+// jobs still get their turn when small ones keep coming in
+const costAgingPerMinute = 10
+
+// Claims a job that's waiting, or whose worker let its lease lapse, for workerID
+// until the lease runs out. Higher priorities go first, then the cheapest job
+// once its cost has been aged by how long it's been waiting
+func SelectJobForUpdate(workerID string, lease time.Duration, timeout ...time.Duration) (*Job, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	var job Job
+	query := `
+		SELECT * FROM jobs
+		WHERE (
+				job_state = 'PENDING'
+				AND (next_attempt_at IS NULL OR Datetime(next_attempt_at) <= Datetime('now', 'localtime'))
+			) OR (
+				job_state IN ( 'PROCESSING', 'DOWNLOADING CLIPS', 'UPLOADING' )
+				AND (lease_expires_at IS NULL OR Datetime(lease_expires_at) <= Datetime('now', 'localtime'))
+			)
+		ORDER BY
+			priority DESC,
+			estimated_cost - (julianday('now', 'localtime') - julianday(created_at)) * 1440 * ? ASC,
+			created_at
+		LIMIT 1;
+	`
+	if err := get(tx, &ctx, &job, query, costAgingPerMinute); err != nil {
+		if strings.Contains(err.Error(), sql.ErrNoRows.Error()) {
+			return nil, fmt.Errorf("QUEUE EMPTY")
+		} else {
+			return nil, utils.ErrorWithTrace(err)
+		}
+	}
+	query = `
+		UPDATE jobs
+		SET
+			job_state = 'PROCESSING',
+			attempts = attempts + 1,
+			worker_id = ?,
+			lease_expires_at = Datetime('now', 'localtime', ?)
+		WHERE id = ?;
+	`
+	if err := exec(tx, &ctx, query, workerID, leaseModifier(lease), job.Id); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := get(tx, &ctx, &job, "SELECT * FROM jobs WHERE id = ?;", job.Id); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &job, nil
+}
+
+var ErrLeaseLost = errors.New("lease lost")
+
+// Pushes the job's lease out another lease from now. ErrLeaseLost means the
+// worker no longer holds the job, it was reclaimed, cancelled or is otherwise done
+func RenewLease(jobID int, workerID string, lease time.Duration, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	var held bool
+	query := `
+		SELECT COUNT(*) > 0 FROM jobs
+		WHERE id = ? AND worker_id = ?
+			AND job_state IN ( 'PROCESSING', 'DOWNLOADING CLIPS', 'UPLOADING' );
+	`
+	if err := get(tx, &ctx, &held, query, jobID, workerID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if !held {
+		return ErrLeaseLost
+	}
+	query = "UPDATE jobs SET lease_expires_at = Datetime('now', 'localtime', ?) WHERE id = ?;"
+	if err := exec(tx, &ctx, query, leaseModifier(lease), jobID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func leaseModifier(lease time.Duration) string {
+	return fmt.Sprintf("+%d seconds", int(lease.Seconds()))
+}
+
+// Saves the job's state and error. ErrLeaseLost means the worker no longer holds
+// the job and nothing was written
+func UpdateJob(job *Job, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+	// a cancelled job stays cancelled no matter what the worker was in the middle of,
+	// unless its video already went out, and a worker that lost its lease has no
+	// say over the job anymore
+	var held bool
+	query := `
+		SELECT COUNT(*) > 0 FROM jobs
+		WHERE id = ? AND (job_state != 'CANCELLED' OR ? = 'FINISHED') AND worker_id IS ?;
+	`
+	if err := get(tx, &ctx, &held, query, job.Id, job.State, job.WorkerID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if !held {
+		return ErrLeaseLost
+	}
+	query = `
+		UPDATE jobs
+		SET (job_state, error_details, refresh_assets) = (:job_state, :error_details, :refresh_assets)
+		WHERE id = :id;
+	`
+	if err := namedExec(tx, &ctx, query, job); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// Puts a job that was interrupted part way through back in the queue. The
+// attempt it was on doesn't count against it
+func RequeueJob(job *Job, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE jobs
+		SET
+			job_state = 'PENDING',
+			attempts = MAX(attempts - 1, 0),
+			worker_id = NULL,
+			lease_expires_at = NULL
+		WHERE id = ? AND job_state != 'CANCELLED' AND worker_id IS ?;
+	`
+	if err := exec(tx, &ctx, query, job.Id, job.WorkerID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := get(tx, &ctx, job, "SELECT * FROM jobs WHERE id = ?;", job.Id); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// Puts the job back in the queue, not to be picked up again until delay has passed
+func RetryJob(job *Job, e error, delay time.Duration, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	errorDetails := e.Error()
+	job.State = "PENDING"
+	job.ErrorDetails = &errorDetails
+	query := `
+		UPDATE jobs
+		SET
+			job_state = 'PENDING',
+			error_details = ?,
+			next_attempt_at = Datetime('now', 'localtime', ?),
+			worker_id = NULL,
+			lease_expires_at = NULL
+		WHERE id = ? AND job_state != 'CANCELLED' AND worker_id IS ?;
+	`
+	modifier := fmt.Sprintf("+%d seconds", int(delay.Seconds()))
+	if err := exec(tx, &ctx, query, errorDetails, modifier, job.Id, job.WorkerID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := get(tx, &ctx, job, "SELECT * FROM jobs WHERE id = ?;", job.Id); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// Marks the job CANCELLED unless it is already done. Returns the job as it stands afterwards
+func CancelJob(slug string, timeout ...time.Duration) (*Job, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	var job Job
+	if err := get(tx, &ctx, &job, "SELECT * FROM jobs WHERE slug = ?;", slug); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if job.IsDone() {
+		return &job, nil
+	}
+	job.State = "CANCELLED"
+	if err := exec(tx, &ctx, "UPDATE jobs SET job_state = 'CANCELLED' WHERE id = ?;", job.Id); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &job, nil
+}
+
+func UpdateJobProgress(job *Job, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+	query := `
+		UPDATE jobs
+		SET (progress_stage, progress_current, progress_total) = (:progress_stage, :progress_current, :progress_total)
+		WHERE id = :id AND worker_id IS :worker_id;
+	`
+	if err := namedExec(tx, &ctx, query, job); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// One play in a job's reel, in the order it plays
+type JobClip struct {
+	Id             int       `db:"id"`
+	JobID          int       `db:"job_id"`
+	GameID         string    `db:"game_id"`
+	EventID        int       `db:"event_id"`
+	Period         *int      `db:"period"`
+	Description    string    `db:"clip_description"`
+	SourceURL      string    `db:"source_url"`
+	Resolution     string    `db:"resolution"`
+	Position       int       `db:"position"`
+	DownloadStatus string    `db:"download_status"`
+	ErrorDetails   *string   `db:"error_details"`
+	FileSize       *int64    `db:"file_size"`
+	FileSHA256     *string   `db:"file_sha256"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+func NewJobClip(jobID int, gameID string, eventID int, period *int, description, sourceURL, resolution string, position int) *JobClip {
+	return &JobClip{
+		JobID:          jobID,
+		GameID:         gameID,
+		EventID:        eventID,
+		Period:         period,
+		Description:    description,
+		SourceURL:      sourceURL,
+		Resolution:     resolution,
+		Position:       position,
+		DownloadStatus: "PENDING",
+	}
+}
+
+// Stores the job's clip manifest, replacing any earlier one
+func InsertJobClips(jobID int, clips []JobClip, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	if err := exec(tx, &ctx, "DELETE FROM job_clips WHERE job_id = ?;", jobID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if len(clips) > 0 {
+		query := `
+			INSERT INTO job_clips (
+				job_id, game_id, event_id, period, clip_description, source_url, resolution, position, download_status, error_details
+			) VALUES (
+				:job_id, :game_id, :event_id, :period, :clip_description, :source_url, :resolution, :position, :download_status, :error_details
+			)
+		`
+		batchSize := 500
+		if err := batchInsert(tx, &ctx, batchSize, query, clips); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// Saves each clip's download status along with the size and checksum of the
+// downloaded file, so a restarted job can tell which clips it already has
+func UpdateJobClipStatuses(clips []JobClip, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE job_clips
+		SET (download_status, error_details, file_size, file_sha256) = (:download_status, :error_details, :file_size, :file_sha256)
+		WHERE job_id = :job_id AND position = :position;
+	`
+	for _, c := range clips {
+		if err := namedExec(tx, &ctx, query, c); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func SelectJobClips(jobID int, timeout ...time.Duration) ([]JobClip, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	clips := []JobClip{}
+	if err := selekt(tx, &ctx, &clips, "SELECT * FROM job_clips WHERE job_id = ? ORDER BY position;", jobID); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return clips, nil
+}
+
+type Video struct {
+	Id          int       `db:"id"`
+	Title       string    `db:"title"`
+	Description string    `db:"video_description"`
+	URL         string    `db:"video_url"`
+	Publisher   string    `db:"publisher"`
+	JobId       int       `db:"job_id"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+func NewVideo(title, description, url, publisher string, jobId int) *Video {
+	return &Video{
+		Title:       title,
+		Description: description,
+		URL:         url,
+		Publisher:   publisher,
+		JobId:       jobId,
+	}
+}
+
+func InsertVideo(video *Video, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT OR IGNORE INTO videos (
+			title, video_description, video_url, publisher, job_id
+		) VALUES (
+			:title, :video_description, :video_url, :publisher, :job_id
+		);
+	`
+	if err := namedExec(tx, &ctx, query, video); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func SelectVideoByJobId(id int, timeout ...time.Duration) (*Video, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+	res := Video{}
+	if err := get(tx, &ctx, &res, "SELECT * FROM videos WHERE job_id = ?;", id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrorWithTrace(fmt.Errorf("unable to find a video associated with this job " + utils.Sad))
+		} else {
+			return nil, utils.ErrorWithTrace(err)
+		}
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &res, nil
+}
+
+func SelectVideosByJobIds(ids []int, timeout ...time.Duration) ([]Video, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := sqlx.In("SELECT * FROM videos WHERE job_id IN (?);", ids)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	query = tx.Rebind(query)
+	videos := []Video{}
+	if err := selekt(tx, &ctx, &videos, query, args...); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return videos, nil
+}
+
+// Records that the job was asked for in the session, creating the session the
+// first time it asks for anything. Asking for the same job twice is a no-op
+func InsertSessionJob(sessionID string, jobID int, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	if err := exec(tx, &ctx, "INSERT INTO sessions (id) VALUES (?) ON CONFLICT (id) DO NOTHING;", sessionID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	query := `
+		INSERT INTO
+			session_jobs (session_id, job_id)
+		VALUES
+			(?, ?)
+		ON CONFLICT (session_id, job_id) DO NOTHING;
+	`
+	if err := exec(tx, &ctx, query, sessionID, jobID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// Jobs asked for in the session, newest first
+func SelectSessionJobs(sessionID string, timeout ...time.Duration) ([]Job, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT
+			j.*
+		FROM
+			jobs j
+			INNER JOIN session_jobs sj ON sj.job_id = j.id
+		WHERE
+			sj.session_id = ?
+		ORDER BY
+			sj.created_at DESC,
+			sj.id DESC;
+	`
+	jobs := []Job{}
+	if err := selekt(tx, &ctx, &jobs, query, sessionID); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return jobs, nil
+}
+
+type BoxScoreScrapingError struct {
+	Id           int       `db:"id"`
+	GameID       string    `db:"game_id"`
+	ErrorDetails string    `db:"error_details"`
+	ErrorStatus  string    `db:"error_status"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+func NewBoxScoreScrapingError(gameID string, err error) *BoxScoreScrapingError {
+	return &BoxScoreScrapingError{
+		GameID:       gameID,
+		ErrorDetails: err.Error(),
+		ErrorStatus:  "PENDING",
+	}
+}
+
+func InsertBoxScoreScrapingErrors(errors []BoxScoreScrapingError, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO box_score_scraping_errors (
+			game_id, error_details, error_status
+		) VALUES (
+			:game_id, :error_details, :error_status
+		)
+	`
+	batchSize := 500
+	if err := batchInsert(tx, &ctx, batchSize, query, errors); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
 
+func UpdateResolvedBoxScoreScrapingErrors(ids []string, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
 	tx, err := dbRW.Beginx()
 	if err != nil {
 		return utils.ErrorWithTrace(err)
@@ -1060,7 +2187,58 @@ func InsertBoxScoreScrapingErrors(errors []BoxScoreScrapingError, timeout ...tim
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO box_score_scraping_errors (
+		UPDATE box_score_scraping_errors SET error_stats = 'RESOLVED' WHERE game_id IN (?);
+	`
+	query, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	query = tx.Rebind(query)
+	if err := exec(tx, &ctx, query, args...); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+type PlayByPlayScrapingError struct {
+	Id           int       `db:"id"`
+	GameID       string    `db:"game_id"`
+	ErrorDetails string    `db:"error_details"`
+	ErrorStatus  string    `db:"error_status"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+func NewPlayByPlayScrapingError(gameID string, err error) *PlayByPlayScrapingError {
+	return &PlayByPlayScrapingError{
+		GameID:       gameID,
+		ErrorDetails: err.Error(),
+		ErrorStatus:  "PENDING",
+	}
+}
+
+func InsertPlayByPlayScrapingErrors(errors []PlayByPlayScrapingError, timeout ...time.Duration) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO play_by_play_scraping_errors (
 			game_id, error_details, error_status
 		) VALUES (
 			:game_id, :error_details, :error_status
@@ -1076,13 +2254,105 @@ func InsertBoxScoreScrapingErrors(errors []BoxScoreScrapingError, timeout ...tim
 	return nil
 }
 
-func UpdateResolvedBoxScoreScrapingErrors(ids []string, timeout ...time.Duration) error {
+func SelectGamesWithPendingPlayByPlayScrapingErrors(timeout ...time.Duration) ([]DatabaseGame, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	// a game can fail across several scrapes, it only needs scraping once
+	query := `
+		SELECT	g.*
+		FROM	games g
+		WHERE	EXISTS (
+					SELECT	1
+					FROM	play_by_play_scraping_errors screrrors
+					WHERE	screrrors.game_id = g.id
+						AND screrrors.error_status = 'PENDING'
+				);
+	`
+	games := []DatabaseGame{}
+	if err := selekt(tx, &ctx, &games, query); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return games, nil
+}
+
+func UpdateResolvedPlayByPlayScrapingErrors(gameIDs []string, timeout ...time.Duration) error {
+	if len(gameIDs) == 0 {
+		return nil
+	}
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := sqlx.In("UPDATE play_by_play_scraping_errors SET error_status = 'RESOLVED' WHERE game_id IN (?);", gameIDs)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	query = tx.Rebind(query)
+	if err := exec(tx, &ctx, query, args...); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+type AssetScrapingError struct {
+	Id             int       `db:"id"`
+	GameID         string    `db:"game_id"`
+	PlayerID       int       `db:"player_id"`
+	Season         string    `db:"season"`
+	ContextMeasure string    `db:"context_measure"`
+	ErrorDetails   string    `db:"error_details"`
+	ErrorStatus    string    `db:"error_status"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+func NewAssetScrapingError(gameID string, playerID int, season, contextMeasure string, err error) *AssetScrapingError {
+	return &AssetScrapingError{
+		GameID:         gameID,
+		PlayerID:       playerID,
+		Season:         season,
+		ContextMeasure: contextMeasure,
+		ErrorDetails:   err.Error(),
+		ErrorStatus:    "PENDING",
+	}
+}
+
+func InsertAssetScrapingErrors(errors []AssetScrapingError, timeout ...time.Duration) error {
+	if len(errors) == 0 {
+		return nil
+	}
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return utils.ErrorWithTrace(err)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
 	defer cancel()
+
 	tx, err := dbRW.Beginx()
 	if err != nil {
 		return utils.ErrorWithTrace(err)
@@ -1090,9 +2360,63 @@ func UpdateResolvedBoxScoreScrapingErrors(ids []string, timeout ...time.Duration
 	defer tx.Rollback()
 
 	query := `
-		UPDATE box_score_scraping_errors SET error_stats = 'RESOLVED' WHERE game_id IN (?);
+		INSERT INTO asset_scraping_errors (
+			game_id, player_id, season, context_measure, error_details, error_status
+		) VALUES (
+			:game_id, :player_id, :season, :context_measure, :error_details, :error_status
+		)
 	`
-	query, args, err := sqlx.In(query, ids)
+	batchSize := 500
+	if err := batchInsert(tx, &ctx, batchSize, query, errors); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func SelectPendingAssetScrapingErrors(timeout ...time.Duration) ([]AssetScrapingError, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	scrapingErrs := []AssetScrapingError{}
+	if err := selekt(tx, &ctx, &scrapingErrs, "SELECT * FROM asset_scraping_errors WHERE error_status = 'PENDING';"); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return scrapingErrs, nil
+}
+
+func UpdateResolvedAssetScrapingErrors(ids []int, timeout ...time.Duration) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+	tx, err := dbRW.Beginx()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := sqlx.In("UPDATE asset_scraping_errors SET error_status = 'RESOLVED' WHERE id IN (?);", ids)
 	if err != nil {
 		return utils.ErrorWithTrace(err)
 	}
@@ -1175,6 +2499,60 @@ func InsertTeams(teams []Team, timeout ...time.Duration) error {
 	return nil
 }
 
+// Teams sorted by city then name, for picking one
+func SelectTeams(timeout ...time.Duration) ([]Team, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	teams := []Team{}
+	if err := selekt(tx, &ctx, &teams, "SELECT * FROM teams ORDER BY city, team_name;"); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return teams, nil
+}
+
+func SelectTeamById(id int, timeout ...time.Duration) (*Team, error) {
+	parsedTimeout, err := parseTimeout(timeout...)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+
+	tx, err := dbRO.Beginx()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	defer tx.Rollback()
+
+	var team Team
+	if err := get(tx, &ctx, &team, "SELECT * FROM teams WHERE id = ?;", id); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &team, nil
+}
+
+// City and name, e.g. Los Angeles Lakers
+func (t *Team) FullName() string {
+	return t.City + " " + t.TeamName
+}
+
 type PlayerSearchInfo struct {
 	PlayerID          int    `db:"player_id"`
 	PlayerName        string `db:"player_name"`
@@ -1248,20 +2626,45 @@ func GetPlayerPlayerSearchInfoBySeason(season string, timeout ...time.Duration)
 
 type Asset struct {
 	Id          int       `db:"id"`
+	GameID      string    `db:"game_id"`
+	EventID     int       `db:"event_id"`
 	Description string    `db:"asset_description"`
-	URL         string    `db:"asset_url"`
+	Uuid        *string   `db:"uuid"`
+	LargeURL    *string   `db:"large_url"`
+	MedURL      *string   `db:"med_url"`
+	SmallURL    *string   `db:"small_url"`
+	Year        *int      `db:"video_year"`
+	Month       *string   `db:"video_month"`
+	Day         *string   `db:"video_day"`
+	Period      *int      `db:"period"`
 	IsDunk      bool      `db:"is_dunk"`
-	BoxScoreID  int       `db:"box_score_id"`
 	CreatedAt   time.Time `db:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at"`
 }
 
-func NewAsset(description, url string, isDunk bool, boxScoreID int) *Asset {
+func NewAsset(
+	gameID string,
+	eventID int,
+	description string,
+	uuid, largeURL, medURL, smallURL *string,
+	year *int,
+	month, day *string,
+	period *int,
+	isDunk bool,
+) *Asset {
 	return &Asset{
+		GameID:      gameID,
+		EventID:     eventID,
 		Description: description,
-		URL:         url,
+		Uuid:        uuid,
+		LargeURL:    largeURL,
+		MedURL:      medURL,
+		SmallURL:    smallURL,
+		Year:        year,
+		Month:       month,
+		Day:         day,
+		Period:      period,
 		IsDunk:      isDunk,
-		BoxScoreID:  boxScoreID,
 	}
 }
 
@@ -1279,90 +2682,207 @@ type AssetsMeasuresEntry struct {
 	Id               int       `db:"id"`
 	ContextMeasureID int       `db:"context_measure_id"`
 	AssetID          int       `db:"asset_id"`
+	PlayerID         int       `db:"player_id"`
 	CreatedAt        time.Time `db:"created_at"`
 	UpdatedAt        time.Time `db:"updated_at"`
 }
 
-func NewAssetsMeasuresEntry(contextMeasureID, assetID int) *AssetsMeasuresEntry {
+func NewAssetsMeasuresEntry(contextMeasureID, assetID, playerID int) *AssetsMeasuresEntry {
 	return &AssetsMeasuresEntry{
 		ContextMeasureID: contextMeasureID,
 		AssetID:          assetID,
+		PlayerID:         playerID,
 	}
 }
 
-func InsertVideoAssets(assets []Asset, contextMeasure ContextMeasure, timeout ...time.Duration) error {
+// Returns the cached assets for a game, player and context measure.
+// found is false when that combination has never been looked up, or when the lookup
+// happened before the NBA was likely done posting clips for the game.
+func SelectCachedAssets(gameID string, playerID int, contextMeasure ContextMeasure, timeout ...time.Duration) (assets []Asset, found bool, err error) {
 	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
-		return utils.ErrorWithTrace(err)
+		return nil, false, utils.ErrorWithTrace(err)
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
 	defer cancel()
-	tx, err := dbRW.Beginx()
+
+	tx, err := dbRO.Beginx()
 	if err != nil {
-		return utils.ErrorWithTrace(err)
+		return nil, false, utils.ErrorWithTrace(err)
 	}
 	defer tx.Rollback()
 
-	insertAssetsQuery := `
-		INSERT OR IGNORE INTO assets (
-			asset_description,
-			asset_url,
-			is_dunk,
-			box_score_id
-		) VALUES (
-			:asset_description,
-			:asset_url,
-			:is_dunk,
-			:box_score_id
-		)
+	lookupQuery := `
+		SELECT	COUNT(*)
+		FROM	asset_lookups l
+			INNER JOIN context_measures cm
+				ON l.context_measure_id = cm.id
+			INNER JOIN games g
+				ON l.game_id = g.id
+		WHERE	l.game_id = ?
+			AND l.player_id = ?
+			AND cm.measure = ?
+			AND Datetime(l.updated_at) >= Datetime(g.game_date, '+1 day');
 	`
-	batchSize := 500
-	if err := batchInsert(tx, &ctx, batchSize, insertAssetsQuery, assets); err != nil {
-		return utils.ErrorWithTrace(err)
+	var count int
+	if err := get(tx, &ctx, &count, lookupQuery, gameID, playerID, contextMeasure.Measure); err != nil {
+		return nil, false, utils.ErrorWithTrace(err)
+	}
+	if count == 0 {
+		return nil, false, nil
+	}
+
+	assetsQuery := `
+		SELECT	a.*
+		FROM	assets a
+			INNER JOIN assets_measures am
+				ON a.id = am.asset_id
+			INNER JOIN context_measures cm
+				ON am.context_measure_id = cm.id
+		WHERE	a.game_id = ?
+			AND am.player_id = ?
+			AND cm.measure = ?
+		ORDER	BY a.event_id ASC;
+	`
+	assets = []Asset{}
+	if err := selekt(tx, &ctx, &assets, assetsQuery, gameID, playerID, contextMeasure.Measure); err != nil {
+		return nil, false, utils.ErrorWithTrace(err)
 	}
-
-	assetURLs := make([]string, 0, len(assets))
-	for _, a := range assets {
-		assetURLs = append(assetURLs, a.URL)
+	if err := commitTx(tx, &ctx); err != nil {
+		return nil, false, utils.ErrorWithTrace(err)
 	}
+	return assets, true, nil
+}
 
-	selectAssetIDQuery, args, err := sqlx.In("SELECT id FROM assets WHERE asset_url IN (?);", assetURLs)
+// Stores the result of a videodetailsasset lookup. Assets are upserted so refreshed urls
+// replace stale ones, and the lookup itself is recorded even when there are no assets.
+func InsertVideoAssets(gameID string, playerID int, contextMeasure ContextMeasure, assets []Asset, timeout ...time.Duration) error {
+	parsedTimeout, err := parseTimeout(timeout...)
 	if err != nil {
 		return utils.ErrorWithTrace(err)
 	}
-	selectAssetIDQuery = tx.Rebind(selectAssetIDQuery)
-
-	// Grab the IDs of the assets we just inserted
-	assetIDs := []int{}
-	if err := selekt(tx, &ctx, &assetIDs, selectAssetIDQuery, args...); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), parsedTimeout)
+	defer cancel()
+	tx, err := dbRW.Beginx()
+	if err != nil {
 		return utils.ErrorWithTrace(err)
 	}
+	defer tx.Rollback()
 
 	// Grab the ID of the related context measure
 	var contextMeasureID int
-	if err := get(tx, &ctx, &contextMeasureID, "SELECT id FROM context_measures WHERE measure = ?;", contextMeasure); err != nil {
+	if err := get(tx, &ctx, &contextMeasureID, "SELECT id FROM context_measures WHERE measure = ?;", contextMeasure.Measure); err != nil {
 		return utils.ErrorWithTrace(err)
 	}
 	if contextMeasureID == 0 {
 		return utils.ErrorWithTrace(fmt.Errorf("could not find id for context measure \"%s\"", contextMeasure.Measure))
 	}
 
-	assetsMeasuresEntries := make([]AssetsMeasuresEntry, 0, len(assetIDs))
-	for _, id := range assetIDs {
-		newEntry := NewAssetsMeasuresEntry(contextMeasureID, id)
-		assetsMeasuresEntries = append(assetsMeasuresEntries, *newEntry)
+	// the lookup is replaced as a whole, plays the NBA has since dropped don't stay cached
+	deleteStaleQuery := `
+		DELETE FROM assets_measures
+		WHERE	context_measure_id = ?
+			AND player_id = ?
+			AND asset_id IN (SELECT id FROM assets WHERE game_id = ?);
+	`
+	if err := exec(tx, &ctx, deleteStaleQuery, contextMeasureID, playerID, gameID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+
+	if len(assets) > 0 {
+		upsertAssetQuery := `
+			INSERT INTO assets (
+				game_id,
+				event_id,
+				asset_description,
+				uuid,
+				large_url,
+				med_url,
+				small_url,
+				video_year,
+				video_month,
+				video_day,
+				period,
+				is_dunk
+			) VALUES (
+				:game_id,
+				:event_id,
+				:asset_description,
+				:uuid,
+				:large_url,
+				:med_url,
+				:small_url,
+				:video_year,
+				:video_month,
+				:video_day,
+				:period,
+				:is_dunk
+			) ON CONFLICT (game_id, event_id) DO UPDATE SET
+				asset_description = excluded.asset_description,
+				uuid = excluded.uuid,
+				large_url = excluded.large_url,
+				med_url = excluded.med_url,
+				small_url = excluded.small_url,
+				period = excluded.period,
+				is_dunk = excluded.is_dunk;
+		`
+		for _, a := range assets {
+			if err := namedExec(tx, &ctx, upsertAssetQuery, a); err != nil {
+				return utils.ErrorWithTrace(err)
+			}
+		}
+
+		eventIDs := make([]int, 0, len(assets))
+		for _, a := range assets {
+			eventIDs = append(eventIDs, a.EventID)
+		}
+		selectAssetIDQuery, args, err := sqlx.In("SELECT id FROM assets WHERE game_id = ? AND event_id IN (?);", gameID, eventIDs)
+		if err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		selectAssetIDQuery = tx.Rebind(selectAssetIDQuery)
+
+		// Grab the IDs of the assets we just upserted
+		assetIDs := []int{}
+		if err := selekt(tx, &ctx, &assetIDs, selectAssetIDQuery, args...); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+
+		assetsMeasuresEntries := make([]AssetsMeasuresEntry, 0, len(assetIDs))
+		for _, id := range assetIDs {
+			newEntry := NewAssetsMeasuresEntry(contextMeasureID, id, playerID)
+			assetsMeasuresEntries = append(assetsMeasuresEntries, *newEntry)
+		}
+
+		insertAssetsMeasuresQuery := `
+			INSERT OR IGNORE INTO assets_measures (
+				context_measure_id,
+				asset_id,
+				player_id
+			) VALUES (
+				:context_measure_id,
+				:asset_id,
+				:player_id
+			);
+		`
+		batchSize := 500
+		if err := batchInsert(tx, &ctx, batchSize, insertAssetsMeasuresQuery, assetsMeasuresEntries); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
 	}
 
-	insertAssetsMeasuresQuery := `
-		INSERT OR IGNORE INTO assets_measures (
-			context_measure_id,
-			asset_id
+	lookupQuery := `
+		INSERT INTO asset_lookups (
+			game_id, player_id, context_measure_id
 		) VALUES (
-			:context_measure_id,
-			:asset_id
-		);
+			?, ?, ?
+		) ON CONFLICT (game_id, player_id, context_measure_id) DO UPDATE SET
+			updated_at = datetime ('now', 'localtime');
 	`
-	if err := batchInsert(tx, &ctx, batchSize, insertAssetsMeasuresQuery, assetsMeasuresEntries); err != nil {
+	if err := exec(tx, &ctx, lookupQuery, gameID, playerID, contextMeasureID); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := commitTx(tx, &ctx); err != nil {
 		return utils.ErrorWithTrace(err)
 	}
 	return nil
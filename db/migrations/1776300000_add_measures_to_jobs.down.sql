@@ -0,0 +1,2 @@
+ALTER TABLE jobs
+DROP COLUMN measures;
\ No newline at end of file
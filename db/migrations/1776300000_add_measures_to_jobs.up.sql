@@ -0,0 +1,2 @@
+ALTER TABLE jobs
+ADD COLUMN measures TEXT NOT NULL DEFAULT "FGA,REB,AST,STL,TOV,BLK";
@@ -0,0 +1,2 @@
+ALTER TABLE jobs
+DROP COLUMN dunks_only;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE jobs
+ADD COLUMN dunks_only BOOLEAN NOT NULL DEFAULT FALSE;
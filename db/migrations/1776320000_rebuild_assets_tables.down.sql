@@ -0,0 +1,32 @@
+DROP TABLE IF EXISTS asset_lookups;
+
+DROP TABLE IF EXISTS assets_measures;
+
+DROP TABLE IF EXISTS assets;
+
+CREATE TABLE
+  IF NOT EXISTS assets (
+    id INTEGER PRIMARY KEY UNIQUE,
+    asset_description TEXT NOT NULL,
+    asset_url TEXT NOT NULL UNIQUE,
+    is_dunk BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime'))
+  );
+
+CREATE INDEX IF NOT EXISTS is_dunk ON assets (is_dunk);
+
+CREATE TABLE
+  IF NOT EXISTS assets_measures (
+    id INTEGER PRIMARY KEY UNIQUE,
+    context_measure_id INTEGER NOT NULL,
+    asset_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime'))
+  );
+
+CREATE INDEX IF NOT EXISTS idx_context_measure_id ON assets_measures (context_measure_id);
+
+CREATE INDEX IF NOT EXISTS idx_asset_id ON assets_measures (asset_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_asset_measure_unique ON assets_measures (context_measure_id, asset_id);
@@ -0,0 +1,94 @@
+-- Nothing ever wrote to the original assets tables, they were missing the
+-- columns needed to key cached lookups by game, player and measure.
+DROP TABLE IF EXISTS assets_measures;
+
+DROP TABLE IF EXISTS assets;
+
+CREATE TABLE
+  IF NOT EXISTS assets (
+    id INTEGER PRIMARY KEY UNIQUE,
+    game_id TEXT NOT NULL,
+    event_id INTEGER NOT NULL,
+    asset_description TEXT NOT NULL,
+    uuid TEXT,
+    large_url TEXT,
+    med_url TEXT,
+    small_url TEXT,
+    video_year INTEGER,
+    video_month TEXT,
+    video_day TEXT,
+    is_dunk BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (game_id) REFERENCES games (id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assets_game_event ON assets (game_id, event_id);
+
+CREATE INDEX IF NOT EXISTS idx_assets_is_dunk ON assets (is_dunk);
+
+CREATE TRIGGER IF NOT EXISTS update_assets AFTER
+UPDATE ON assets FOR EACH ROW BEGIN
+UPDATE assets
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
+
+CREATE TABLE
+  IF NOT EXISTS assets_measures (
+    id INTEGER PRIMARY KEY UNIQUE,
+    context_measure_id INTEGER NOT NULL,
+    asset_id INTEGER NOT NULL,
+    player_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (context_measure_id) REFERENCES context_measures (id),
+    FOREIGN KEY (asset_id) REFERENCES assets (id),
+    FOREIGN KEY (player_id) REFERENCES players (id)
+  );
+
+CREATE INDEX IF NOT EXISTS idx_assets_measures_asset_id ON assets_measures (asset_id);
+
+CREATE INDEX IF NOT EXISTS idx_assets_measures_player_id ON assets_measures (player_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assets_measures_unique ON assets_measures (context_measure_id, asset_id, player_id);
+
+CREATE TRIGGER IF NOT EXISTS update_assets_measures AFTER
+UPDATE ON assets_measures FOR EACH ROW BEGIN
+UPDATE assets_measures
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
+
+-- One row per videodetailsasset query we've cached, so lookups that returned
+-- zero clips don't get re-queried either.
+CREATE TABLE
+  IF NOT EXISTS asset_lookups (
+    id INTEGER PRIMARY KEY UNIQUE,
+    game_id TEXT NOT NULL,
+    player_id INTEGER NOT NULL,
+    context_measure_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (game_id) REFERENCES games (id),
+    FOREIGN KEY (player_id) REFERENCES players (id),
+    FOREIGN KEY (context_measure_id) REFERENCES context_measures (id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_asset_lookups_unique ON asset_lookups (game_id, player_id, context_measure_id);
+
+CREATE TRIGGER IF NOT EXISTS update_asset_lookups AFTER
+UPDATE ON asset_lookups FOR EACH ROW BEGIN
+UPDATE asset_lookups
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS asset_scraping_errors;
\ No newline at end of file
@@ -0,0 +1,27 @@
+CREATE TABLE
+  IF NOT EXISTS asset_scraping_errors (
+    id INTEGER PRIMARY KEY UNIQUE,
+    game_id TEXT NOT NULL,
+    player_id INTEGER NOT NULL,
+    season TEXT NOT NULL,
+    context_measure TEXT NOT NULL,
+    error_details TEXT NOT NULL,
+    error_status TEXT NOT NULL DEFAULT "PENDING",
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (game_id) REFERENCES games (id)
+  );
+
+CREATE INDEX IF NOT EXISTS idx_asset_scraping_errors_game_id ON asset_scraping_errors (game_id);
+
+CREATE INDEX IF NOT EXISTS idx_asset_scraping_errors_error_status ON asset_scraping_errors (error_status);
+
+CREATE TRIGGER IF NOT EXISTS update_asset_scraping_errors_modtime AFTER
+UPDATE ON asset_scraping_errors FOR EACH ROW BEGIN
+UPDATE asset_scraping_errors
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
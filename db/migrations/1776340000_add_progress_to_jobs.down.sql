@@ -0,0 +1,8 @@
+ALTER TABLE jobs
+DROP COLUMN progress_total;
+
+ALTER TABLE jobs
+DROP COLUMN progress_current;
+
+ALTER TABLE jobs
+DROP COLUMN progress_stage;
@@ -0,0 +1,8 @@
+ALTER TABLE jobs
+ADD COLUMN progress_stage TEXT NOT NULL DEFAULT "";
+
+ALTER TABLE jobs
+ADD COLUMN progress_current INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+ADD COLUMN progress_total INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE videos
+DROP COLUMN publisher;
+
+ALTER TABLE videos
+RENAME COLUMN video_url TO youtube_url;
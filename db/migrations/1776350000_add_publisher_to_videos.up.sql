@@ -0,0 +1,5 @@
+ALTER TABLE videos
+RENAME COLUMN youtube_url TO video_url;
+
+ALTER TABLE videos
+ADD COLUMN publisher TEXT NOT NULL DEFAULT "youtube";
@@ -0,0 +1,5 @@
+ALTER TABLE jobs
+DROP COLUMN next_attempt_at;
+
+ALTER TABLE jobs
+DROP COLUMN attempts;
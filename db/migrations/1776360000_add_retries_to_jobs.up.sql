@@ -0,0 +1,5 @@
+ALTER TABLE jobs
+ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+ADD COLUMN next_attempt_at TIMESTAMP;
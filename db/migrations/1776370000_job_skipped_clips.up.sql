@@ -0,0 +1,23 @@
+CREATE TABLE
+  IF NOT EXISTS job_skipped_clips (
+    id INTEGER PRIMARY KEY UNIQUE,
+    job_id INTEGER NOT NULL,
+    game_id TEXT NOT NULL,
+    event_id INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (job_id) REFERENCES jobs (id)
+  );
+
+CREATE INDEX IF NOT EXISTS idx_job_skipped_clips_job_id ON job_skipped_clips (job_id);
+
+CREATE TRIGGER IF NOT EXISTS update_job_skipped_clips_modtime AFTER
+UPDATE ON job_skipped_clips FOR EACH ROW BEGIN
+UPDATE job_skipped_clips
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
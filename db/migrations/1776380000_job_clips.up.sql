@@ -0,0 +1,66 @@
+ALTER TABLE assets
+ADD COLUMN period INTEGER;
+
+CREATE TABLE
+  IF NOT EXISTS job_clips (
+    id INTEGER PRIMARY KEY UNIQUE,
+    job_id INTEGER NOT NULL,
+    game_id TEXT NOT NULL,
+    event_id INTEGER NOT NULL,
+    period INTEGER,
+    clip_description TEXT NOT NULL,
+    source_url TEXT NOT NULL,
+    resolution TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    download_status TEXT NOT NULL DEFAULT "PENDING",
+    error_details TEXT,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (job_id) REFERENCES jobs (id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_clips_job_id_position ON job_clips (job_id, position);
+
+CREATE TRIGGER IF NOT EXISTS update_job_clips_modtime AFTER
+UPDATE ON job_clips FOR EACH ROW BEGIN
+UPDATE job_clips
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
+
+-- skipped clips are now just failed rows in the manifest
+INSERT INTO
+  job_clips (
+    job_id,
+    game_id,
+    event_id,
+    clip_description,
+    source_url,
+    resolution,
+    position,
+    download_status,
+    error_details
+  )
+SELECT
+  job_id,
+  game_id,
+  event_id,
+  "",
+  "",
+  "",
+  ROW_NUMBER() OVER (
+    PARTITION BY
+      job_id
+    ORDER BY
+      game_id,
+      event_id
+  ) - 1,
+  "FAILED",
+  reason
+FROM
+  job_skipped_clips;
+
+DROP TABLE IF EXISTS job_skipped_clips;
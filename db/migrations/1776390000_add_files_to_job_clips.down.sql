@@ -0,0 +1,5 @@
+ALTER TABLE job_clips
+DROP COLUMN file_sha256;
+
+ALTER TABLE job_clips
+DROP COLUMN file_size;
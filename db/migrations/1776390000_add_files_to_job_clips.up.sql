@@ -0,0 +1,5 @@
+ALTER TABLE job_clips
+ADD COLUMN file_size INTEGER;
+
+ALTER TABLE job_clips
+ADD COLUMN file_sha256 TEXT;
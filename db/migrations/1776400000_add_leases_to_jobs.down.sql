@@ -0,0 +1,5 @@
+ALTER TABLE jobs
+DROP COLUMN lease_expires_at;
+
+ALTER TABLE jobs
+DROP COLUMN worker_id;
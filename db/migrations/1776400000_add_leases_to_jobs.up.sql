@@ -0,0 +1,5 @@
+ALTER TABLE jobs
+ADD COLUMN worker_id TEXT;
+
+ALTER TABLE jobs
+ADD COLUMN lease_expires_at TIMESTAMP;
@@ -0,0 +1,5 @@
+ALTER TABLE jobs
+DROP COLUMN estimated_cost;
+
+ALTER TABLE jobs
+DROP COLUMN priority;
@@ -0,0 +1,10 @@
+ALTER TABLE jobs
+ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+ADD COLUMN estimated_cost INTEGER NOT NULL DEFAULT 0;
+
+-- games x players x measures, counted off the comma separated lists
+UPDATE jobs
+SET
+  estimated_cost = (LENGTH(games) - LENGTH(REPLACE(games, ',', '')) + 1) * (LENGTH(players) - LENGTH(REPLACE(players, ',', '')) + 1) * (LENGTH(measures) - LENGTH(REPLACE(measures, ',', '')) + 1);
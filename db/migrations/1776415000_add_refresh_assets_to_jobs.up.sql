@@ -0,0 +1,2 @@
+ALTER TABLE jobs
+ADD COLUMN refresh_assets BOOLEAN NOT NULL DEFAULT FALSE;
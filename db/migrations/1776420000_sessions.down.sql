@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS session_jobs;
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,40 @@
+-- anonymous for now, accounts can hang off sessions later
+CREATE TABLE
+  IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY UNIQUE,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime'))
+  );
+
+CREATE TRIGGER IF NOT EXISTS update_sessions_modtime AFTER
+UPDATE ON sessions FOR EACH ROW BEGIN
+UPDATE sessions
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
+
+CREATE TABLE
+  IF NOT EXISTS session_jobs (
+    id INTEGER PRIMARY KEY UNIQUE,
+    session_id TEXT NOT NULL,
+    job_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (session_id) REFERENCES sessions (id),
+    FOREIGN KEY (job_id) REFERENCES jobs (id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_jobs_session_id_job_id ON session_jobs (session_id, job_id);
+
+CREATE TRIGGER IF NOT EXISTS update_session_jobs_modtime AFTER
+UPDATE ON session_jobs FOR EACH ROW BEGIN
+UPDATE session_jobs
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
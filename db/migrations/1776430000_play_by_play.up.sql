@@ -0,0 +1,43 @@
+CREATE TABLE
+  IF NOT EXISTS play_by_play (
+    id INTEGER PRIMARY KEY UNIQUE,
+    game_id TEXT NOT NULL,
+    action_number INTEGER NOT NULL,
+    action_id INTEGER,
+    period INTEGER NOT NULL,
+    clock TEXT NOT NULL,
+    seconds_remaining REAL NOT NULL,
+    team_id INTEGER,
+    person_id INTEGER,
+    player_name TEXT,
+    action_type TEXT NOT NULL,
+    sub_type TEXT,
+    play_description TEXT,
+    shot_distance INTEGER,
+    shot_result TEXT,
+    shot_value INTEGER,
+    is_field_goal BOOLEAN NOT NULL DEFAULT FALSE,
+    x INTEGER,
+    y INTEGER,
+    location TEXT,
+    score_home INTEGER NOT NULL,
+    score_away INTEGER NOT NULL,
+    video_available BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (game_id) REFERENCES games (id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_play_by_play_game_id_action_number ON play_by_play (game_id, action_number);
+
+CREATE INDEX IF NOT EXISTS idx_play_by_play_person_id ON play_by_play (person_id);
+
+CREATE TRIGGER IF NOT EXISTS update_play_by_play_modtime AFTER
+UPDATE ON play_by_play FOR EACH ROW BEGIN
+UPDATE play_by_play
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
@@ -0,0 +1,8 @@
+ALTER TABLE play_by_play
+DROP COLUMN block_person_id;
+
+ALTER TABLE play_by_play
+DROP COLUMN steal_person_id;
+
+ALTER TABLE play_by_play
+DROP COLUMN assist_person_id;
@@ -0,0 +1,8 @@
+ALTER TABLE play_by_play
+ADD COLUMN assist_person_id INTEGER;
+
+ALTER TABLE play_by_play
+ADD COLUMN steal_person_id INTEGER;
+
+ALTER TABLE play_by_play
+ADD COLUMN block_person_id INTEGER;
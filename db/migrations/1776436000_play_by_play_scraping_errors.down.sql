@@ -0,0 +1 @@
+DROP TABLE IF EXISTS play_by_play_scraping_errors;
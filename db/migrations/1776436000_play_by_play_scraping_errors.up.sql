@@ -0,0 +1,24 @@
+CREATE TABLE
+  IF NOT EXISTS play_by_play_scraping_errors (
+    id INTEGER PRIMARY KEY UNIQUE,
+    game_id TEXT NOT NULL,
+    error_details TEXT NOT NULL,
+    error_status TEXT NOT NULL DEFAULT "PENDING",
+    created_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    updated_at TIMESTAMP DEFAULT (datetime ('now', 'localtime')),
+    FOREIGN KEY (game_id) REFERENCES games (id)
+  );
+
+CREATE INDEX IF NOT EXISTS idx_play_by_play_scraping_errors_game_id ON play_by_play_scraping_errors (game_id);
+
+CREATE INDEX IF NOT EXISTS idx_play_by_play_scraping_errors_error_status ON play_by_play_scraping_errors (error_status);
+
+CREATE TRIGGER IF NOT EXISTS update_play_by_play_scraping_errors_modtime AFTER
+UPDATE ON play_by_play_scraping_errors FOR EACH ROW BEGIN
+UPDATE play_by_play_scraping_errors
+SET
+  updated_at = datetime ('now', 'localtime')
+WHERE
+  id = NEW.id;
+
+END;
@@ -0,0 +1,17 @@
+ALTER TABLE jobs
+DROP COLUMN end_period;
+
+ALTER TABLE jobs
+DROP COLUMN start_period;
+
+ALTER TABLE jobs
+DROP COLUMN period;
+
+ALTER TABLE jobs
+DROP COLUMN point_diff;
+
+ALTER TABLE jobs
+DROP COLUMN ahead_behind;
+
+ALTER TABLE jobs
+DROP COLUMN clutch_time;
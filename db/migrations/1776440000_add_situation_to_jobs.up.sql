@@ -0,0 +1,17 @@
+ALTER TABLE jobs
+ADD COLUMN clutch_time TEXT NOT NULL DEFAULT "";
+
+ALTER TABLE jobs
+ADD COLUMN ahead_behind TEXT NOT NULL DEFAULT "";
+
+ALTER TABLE jobs
+ADD COLUMN point_diff INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+ADD COLUMN period INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+ADD COLUMN start_period INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+ADD COLUMN end_period INTEGER NOT NULL DEFAULT 0;
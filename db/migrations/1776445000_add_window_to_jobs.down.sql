@@ -0,0 +1,4 @@
+ALTER TABLE jobs
+DROP COLUMN date_to;
+ALTER TABLE jobs
+DROP COLUMN date_from;
@@ -0,0 +1,4 @@
+ALTER TABLE jobs
+ADD COLUMN date_from TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs
+ADD COLUMN date_to TEXT NOT NULL DEFAULT '';
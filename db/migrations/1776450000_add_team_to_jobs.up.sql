@@ -0,0 +1,2 @@
+ALTER TABLE jobs
+ADD COLUMN team_id INTEGER REFERENCES teams (id);
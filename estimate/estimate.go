@@ -0,0 +1,115 @@
+package estimate
+
+import (
+	"fmt"
+	"slices"
+
+	"dunkod/db"
+	"dunkod/nba"
+	"dunkod/utils"
+)
+
+// Clips from the NBA run about this long
+const SecondsPerClip = 8
+
+// Rough size of a job, worked out from the box scores we've already scraped
+// rather than asking the NBA for every clip
+type Estimate struct {
+	// asset lookups the job makes, see db.LookupCount
+	Lookups int
+	Clips   int
+	Seconds int
+	// false when none of the player games have a box score yet, so Clips and Seconds mean nothing
+	Known bool
+	// true when the job only wants plays from a game situation. Box scores count
+	// every play, so Clips and Seconds are as many as there could be
+	Narrowed bool
+}
+
+// Measures whose plays are all included in one of the listed measures, so they
+// aren't counted again when both are picked
+var subsetOf = map[nba.VideoDetailsAssetContextMeasure][]nba.VideoDetailsAssetContextMeasure{
+	nba.VideoDetailsAssetContextMeasures.FGM:  {nba.VideoDetailsAssetContextMeasures.FGA},
+	nba.VideoDetailsAssetContextMeasures.FG3M: {nba.VideoDetailsAssetContextMeasures.FGA, nba.VideoDetailsAssetContextMeasures.FGM, nba.VideoDetailsAssetContextMeasures.FG3A},
+	nba.VideoDetailsAssetContextMeasures.FG3A: {nba.VideoDetailsAssetContextMeasures.FGA},
+	nba.VideoDetailsAssetContextMeasures.OREB: {nba.VideoDetailsAssetContextMeasures.REB},
+	nba.VideoDetailsAssetContextMeasures.DREB: {nba.VideoDetailsAssetContextMeasures.REB},
+}
+
+// For a team, teamID is set and playerIDs are whoever played for it, which is
+// only used to count the plays
+func ForRequest(gameIDs, playerIDs []string, teamID int, measures []nba.VideoDetailsAssetContextMeasure, opts nba.VideoDetailsAssetOptions, window nba.VideoDetailsAssetWindow) (*Estimate, error) {
+	totals, err := db.SelectBoxScoreTotals(gameIDs, playerIDs)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+
+	clips := countMeasures(totals, measures)
+
+	players := len(playerIDs)
+	if teamID != 0 {
+		players = 1
+	}
+	return &Estimate{
+		Lookups:  db.LookupCount(gameIDs, players, len(measures), window),
+		Clips:    int(clips),
+		Seconds:  int(clips) * SecondsPerClip,
+		Known:    totals.PlayerGames > 0,
+		Narrowed: opts.Narrows(),
+	}, nil
+}
+
+// Plays across all the measures, leaving out measures a bigger picked one already covers
+func countMeasures(totals *db.BoxScoreTotals, measures []nba.VideoDetailsAssetContextMeasure) float64 {
+	clips := 0.0
+	for _, m := range measures {
+		if slices.ContainsFunc(subsetOf[m], func(superset nba.VideoDetailsAssetContextMeasure) bool {
+			return slices.Contains(measures, superset)
+		}) {
+			continue
+		}
+		clips += count(totals, m)
+	}
+	return clips
+}
+
+func count(totals *db.BoxScoreTotals, m nba.VideoDetailsAssetContextMeasure) float64 {
+	switch m {
+	case nba.VideoDetailsAssetContextMeasures.FGM:
+		return totals.FGM
+	case nba.VideoDetailsAssetContextMeasures.FGA:
+		return totals.FGA
+	case nba.VideoDetailsAssetContextMeasures.FG3M:
+		return totals.FG3M
+	case nba.VideoDetailsAssetContextMeasures.FG3A:
+		return totals.FG3A
+	case nba.VideoDetailsAssetContextMeasures.FTM:
+		return totals.FTM
+	case nba.VideoDetailsAssetContextMeasures.OREB:
+		return totals.OREB
+	case nba.VideoDetailsAssetContextMeasures.DREB:
+		return totals.DREB
+	case nba.VideoDetailsAssetContextMeasures.REB:
+		return totals.REB
+	case nba.VideoDetailsAssetContextMeasures.AST:
+		return totals.AST
+	case nba.VideoDetailsAssetContextMeasures.STL:
+		return totals.STL
+	case nba.VideoDetailsAssetContextMeasures.BLK:
+		return totals.BLK
+	case nba.VideoDetailsAssetContextMeasures.TOV:
+		return totals.TOV
+	case nba.VideoDetailsAssetContextMeasures.PF:
+		return totals.PF
+	default:
+		return 0
+	}
+}
+
+// Reel length for people, e.g. "4 min" or "40 sec"
+func (e *Estimate) Length() string {
+	if e.Seconds < 60 {
+		return fmt.Sprintf("%d sec", e.Seconds)
+	}
+	return fmt.Sprintf("%d min", (e.Seconds+30)/60)
+}
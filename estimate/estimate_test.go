@@ -0,0 +1,33 @@
+package estimate
+
+import (
+	"testing"
+
+	"dunkod/db"
+	"dunkod/nba"
+)
+
+func TestCountMeasures(t *testing.T) {
+	m := nba.VideoDetailsAssetContextMeasures
+	totals := &db.BoxScoreTotals{PlayerGames: 1, FGM: 10, FGA: 20, FG3M: 3, FG3A: 8, FTM: 5, OREB: 2, DREB: 6, REB: 8, AST: 7, STL: 1, BLK: 2}
+	tests := []struct {
+		name     string
+		measures []nba.VideoDetailsAssetContextMeasure
+		want     float64
+	}{
+		{"nothing", nil, 0},
+		{"one measure", []nba.VideoDetailsAssetContextMeasure{m.FGM}, 10},
+		{"makes are part of the attempts", []nba.VideoDetailsAssetContextMeasure{m.FGM, m.FGA}, 20},
+		{"threes are part of the attempts", []nba.VideoDetailsAssetContextMeasure{m.FG3M, m.FG3A, m.FGA}, 20},
+		{"made threes are part of the makes", []nba.VideoDetailsAssetContextMeasure{m.FGM, m.FG3M}, 10},
+		{"made threes are part of the three attempts", []nba.VideoDetailsAssetContextMeasure{m.FG3M, m.FG3A}, 8},
+		{"rebounds cover both ends", []nba.VideoDetailsAssetContextMeasure{m.OREB, m.DREB, m.REB}, 8},
+		{"both ends without the total", []nba.VideoDetailsAssetContextMeasure{m.OREB, m.DREB}, 8},
+		{"unrelated measures add up", []nba.VideoDetailsAssetContextMeasure{m.FGM, m.AST, m.STL, m.BLK, m.FTM}, 25},
+	}
+	for _, tt := range tests {
+		if got := countMeasures(totals, tt.measures); got != tt.want {
+			t.Errorf("%s: countMeasures(%v) = %v, want %v", tt.name, tt.measures, got, tt.want)
+		}
+	}
+}
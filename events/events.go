@@ -0,0 +1,96 @@
+package events
+
+import (
+	"sync"
+)
+
+const subscriberBuffer = 16
+
+type JobEvent struct {
+	Slug            string
+	State           string
+	ErrorDetails    *string
+	ProgressStage   string
+	ProgressCurrent int
+	ProgressTotal   int
+}
+
+func NewJobEvent(slug, state string, errorDetails *string, progressStage string, progressCurrent, progressTotal int) *JobEvent {
+	return &JobEvent{
+		Slug:            slug,
+		State:           state,
+		ErrorDetails:    errorDetails,
+		ProgressStage:   progressStage,
+		ProgressCurrent: progressCurrent,
+		ProgressTotal:   progressTotal,
+	}
+}
+
+// In-process pub/sub for job updates, keyed by job slug
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan JobEvent]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[string]map[chan JobEvent]struct{}{},
+	}
+}
+
+// Returns a channel of events for the slug and a func to stop listening.
+// The channel is closed once unsubscribed
+func (b *Broker) Subscribe(slug string) (<-chan JobEvent, func()) {
+	ch := make(chan JobEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[slug] == nil {
+		b.subscribers[slug] = map[chan JobEvent]struct{}{}
+	}
+	b.subscribers[slug][ch] = struct{}{}
+	b.mu.Unlock()
+
+	once := sync.Once{}
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[slug], ch)
+			if len(b.subscribers[slug]) == 0 {
+				delete(b.subscribers, slug)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Never blocks, a slow subscriber loses its oldest event rather than holding up the worker
+func (b *Broker) Publish(e JobEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[e.Slug] {
+		select {
+		case ch <- e:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+var defaultBroker = NewBroker()
+
+func Subscribe(slug string) (<-chan JobEvent, func()) {
+	return defaultBroker.Subscribe(slug)
+}
+
+func Publish(e JobEvent) {
+	defaultBroker.Publish(e)
+}
@@ -1,142 +1,475 @@
 package jobs
 
 import (
-	"crypto/md5"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"math"
-	"math/rand"
+	"math/rand/v2"
+	"net/http"
 	"os"
 	"os/exec"
-	"regexp"
+	"path/filepath"
 	"slices"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"dunkod/cache"
+	"dunkod/config"
 	"dunkod/db"
+	"dunkod/events"
 	"dunkod/nba"
+	"dunkod/publish"
 	"dunkod/utils"
-	"dunkod/youtube"
 )
 
 const titleCharLimit = 100
 const descCharLimit = 5000
 
 type Worker struct {
-	Id     int
-	Quit   chan bool
-	IsIdle bool
+	Id int
+	// unique across every instance sharing the database, it's what holds the lease on a job
+	Name      string
+	Publisher publish.Publisher
+
+	mu    sync.Mutex
+	job   *db.Job
+	since time.Time
 }
 
-func NewWorker(id int) *Worker {
+func NewWorker(id int, publisher publish.Publisher) *Worker {
 	return &Worker{
-		Id:     id,
-		Quit:   make(chan bool, 1),
-		IsIdle: true,
+		Id:        id,
+		Name:      fmt.Sprintf("%s/%d", instanceID, id),
+		Publisher: publisher,
+		since:     time.Now(),
 	}
 }
 
+// Tells this process's workers apart from those of other instances
+var instanceID = newInstanceID()
+
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "dunkod"
+	}
+	return fmt.Sprintf("%s-%d-%04x", host, os.Getpid(), rand.N(0x10000))
+}
+
+// What a worker is up to and since when
+type WorkerStatus struct {
+	Id      int
+	Name    string
+	Idle    bool
+	JobSlug string
+	Since   time.Time
+}
+
+func (w *Worker) Status() WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := WorkerStatus{
+		Id:    w.Id,
+		Name:  w.Name,
+		Idle:  w.job == nil,
+		Since: w.since,
+	}
+	if w.job != nil {
+		status.JobSlug = w.job.Slug
+	}
+	return status
+}
+
+// nil frees the worker up
+func (w *Worker) assign(job *db.Job) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.job = job
+	w.since = time.Now()
+}
+
 func (w *Worker) DoYourJob(job *db.Job) {
-	defer func() { w.IsIdle = true }()
+	ctx, done := track(job.Slug)
+	defer done()
+	stopHeartbeat := heartbeat(ctx, job.Id, w.Name, job.Slug, *config.JobLease)
+	defer stopHeartbeat()
+	dir := workDir(job)
+	defer func() {
+		// a job that's going to be retried keeps whatever it got done for next time
+		if job.IsDone() {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+	// the job may have been cancelled between being picked up and being tracked
+	if latest, err := db.SelectJobBySlug(job.Slug); err == nil && latest.State == "CANCELLED" {
+		job.State = latest.State
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ohNo(ctx, job, utils.ErrorWithTrace(err))
+		return
+	}
+
 	gameIDs := job.GamesIDs()
 	playerIDs := job.PlayerIDs()
-
-	assets, err := getAssets(job.Season, gameIDs, playerIDs)
+	measures, err := nba.ParseContextMeasures(job.ContextMeasures())
 	if err != nil {
-		errorDetails := fmt.Errorf("WorkerID: %d\n\tJob Hash: %s\n\tError: %s", w.Id, job.Hash, err.Error())
-		log.Println(errorDetails.Error())
-		if err := job.OhNo(errorDetails); err != nil {
-			log.Println(err)
-		}
+		ohNo(ctx, job, err)
 		return
 	}
 
-	assetURLs := make([]string, 0, len(assets))
-	for _, a := range assets {
-		if a.LargeUrl != nil {
-			assetURLs = append(assetURLs, *a.LargeUrl)
-		} else if a.MedUrl != nil {
-			assetURLs = append(assetURLs, *a.MedUrl)
-		} else if a.SmallUrl != nil {
-			assetURLs = append(assetURLs, *a.SmallUrl)
+	progress := newProgress(job)
+	// a job that already has a manifest was interrupted, pick up where it left off
+	clips, err := db.SelectJobClips(job.Id)
+	if err != nil {
+		ohNo(ctx, job, err)
+		return
+	}
+	if len(clips) == 0 {
+		teamID := 0
+		if job.TeamID != nil {
+			teamID = *job.TeamID
+		}
+		assets, err := getAssets(ctx, job.Season, gameIDs, playerIDs, teamID, measures, job.AssetOptions(), job.Window(), job.RefreshAssets, progress)
+		if err != nil {
+			errorDetails := fmt.Errorf("WorkerID: %d\n\tJob Hash: %s\n\tError: %w", w.Id, job.Hash, err)
+			log.Println(errorDetails.Error())
+			ohNo(ctx, job, errorDetails)
+			return
+		}
+		// the cache is fresh now, so it's good for any later attempt. This is
+		// saved along with the next state
+		job.RefreshAssets = false
+		if job.DunksOnly {
+			assets = nba.FilterDunks(assets)
+		}
+		clips = newClips(job.Id, assets)
+		if len(clips) == 0 {
+			// nothing went wrong, there just aren't any plays to make a reel out of
+			if err := setState(ctx, job, "NO CLIPS"); err != nil {
+				ohNo(ctx, job, err)
+			}
+			return
+		}
+		// narrowed jobs get past the estimate since box scores can't count their
+		// plays, so the cap is held here on what was actually found
+		if len(clips) > *config.MaxClips {
+			ohNo(ctx, job, fmt.Errorf("found %d clips and the most one reel can have is %d, pick fewer "+utils.Sad, len(clips), *config.MaxClips))
+			return
+		}
+		if err := db.InsertJobClips(job.Id, clips); err != nil {
+			ohNo(ctx, job, err)
+			return
 		}
 	}
 
-	job.State = "DOWNLOADING CLIPS"
-	if err := db.UpdateJob(job); err != nil {
-		if err := job.OhNo(err); err != nil {
-			log.Println(err)
-		}
+	if err := setState(ctx, job, "DOWNLOADING CLIPS"); err != nil {
+		ohNo(ctx, job, err)
 		return
 	}
 
-	sortAssetURLs(&assetURLs)
-	vidPath, err := downloadAndConcat(assetURLs)
-	defer func() { _ = os.Remove(vidPath) }()
-	if err != nil {
-		errorDetails := fmt.Errorf("WorkerID: %d\n\tJob Hash: %s\n\tError: %s", w.Id, job.Hash, err.Error())
-		if err := job.OhNo(errorDetails); err != nil {
-			log.Println(err)
+	// the reel only gets written once the concat has finished, so if it's there it's whole
+	vidPath := filepath.Join(dir, job.Hash+".mp4")
+	if _, err := os.Stat(vidPath); err != nil {
+		if err := downloadAndConcat(ctx, dir, vidPath, clips, *config.ClipThreshold, progress); err != nil {
+			errorDetails := fmt.Errorf("WorkerID: %d\n\tJob Hash: %s\n\tError: %w", w.Id, job.Hash, err)
+			ohNo(ctx, job, errorDetails)
+			return
 		}
-		return
 	}
 
 	playerNames, err := db.SelectPlayerNamesById(playerIDs)
 	if err != nil {
 		log.Println(err)
-		if err := job.OhNo(err); err != nil {
-			log.Println(err)
-		}
+		ohNo(ctx, job, err)
 		return
 	}
 
 	games, err := db.SelectGamesById(gameIDs)
 	if err != nil {
 		log.Println(err)
-		if err := job.OhNo(err); err != nil {
+		ohNo(ctx, job, err)
+		return
+	}
+
+	teamName := ""
+	if job.TeamID != nil {
+		team, err := db.SelectTeamById(*job.TeamID)
+		if err != nil {
 			log.Println(err)
+			ohNo(ctx, job, err)
+			return
 		}
-		return
+		teamName = team.FullName()
 	}
 
-	title := makeTitle(job.Season, games, playerNames)
-	desc := makeDescription(job.Season, games, playerNames)
+	title := makeTitle(job.Season, games, playerNames, teamName)
+	desc := makeDescription(job.Season, games, playerNames, teamName, measures, job.DunksOnly, job.AssetOptions().Labels())
 
-	job.State = "UPLOADING"
-	if err := db.UpdateJob(job); err != nil {
+	if err := setState(ctx, job, "UPLOADING"); err != nil {
 		log.Println(err)
-		if err := job.OhNo(err); err != nil {
-			log.Println(err)
+		ohNo(ctx, job, err)
+		return
+	}
+	progress.start(db.ProgressUpload, 100)
+	url, err := w.Publisher.Publish(ctx, vidPath, title, desc, []string{"NBA", "nba", "basketball", "highlights", "sports", "Please Hire Me"}, func(sent, total int64) {
+		if total > 0 {
+			progress.set(int(sent * 100 / total))
 		}
+	})
+	if err != nil {
+		ohNo(ctx, job, err)
+		return
+	}
+	// the video is out there now, a cancel that came in since doesn't take that back
+	ctx = context.WithoutCancel(ctx)
+	if err := db.InsertVideo(db.NewVideo(title, desc, url, w.Publisher.Name(), job.Id)); err != nil {
+		ohNo(ctx, job, err)
+		return
+	}
+	if err := setState(ctx, job, "FINISHED"); err != nil {
+		log.Println(err)
+		ohNo(ctx, job, err)
 		return
 	}
-	url, err := youtube.UploadFile(vidPath, title, desc, []string{"NBA", "nba", "basketball", "highlights", "sports", "Please Hire Me"})
+}
+
+// Cancel funcs for the jobs workers are currently on, keyed by slug. The cause
+// says whether the job was cancelled or just interrupted
+var running = map[string]context.CancelCauseFunc{}
+var runningMu = sync.Mutex{}
+
+var errCancelled = errors.New("job cancelled")
+var errInterrupted = errors.New("job interrupted by shutdown")
+var errLeaseLost = errors.New("job lease lost")
+
+func track(slug string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	runningMu.Lock()
+	running[slug] = cancel
+	runningMu.Unlock()
+	return ctx, func() {
+		runningMu.Lock()
+		delete(running, slug)
+		runningMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Stops whichever worker is on the job, cause decides what ohNo does about it
+func stop(slug string, cause error) bool {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	cancel, ok := running[slug]
+	if ok {
+		cancel(cause)
+	}
+	return ok
+}
+
+// Renews the job's lease every third of the lease until the returned func is
+// called. Once the lease can't be renewed the job isn't the worker's anymore,
+// either it was cancelled, possibly through another instance, or it took so long
+// to renew that someone else took over, so the worker is stopped
+func heartbeat(ctx context.Context, jobID int, workerID, slug string, lease time.Duration) func() {
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := db.RenewLease(jobID, workerID, lease)
+			if errors.Is(err, db.ErrLeaseLost) {
+				stopLost(workerID, slug)
+				return
+			} else if err != nil {
+				// keep trying, the lease has some slack
+				log.Println(err)
+			}
+		}
+	}()
+	return func() { close(quit) }
+}
+
+// Stops the worker on a job it no longer holds, telling a cancel apart from
+// someone else taking the job over
+func stopLost(workerID, slug string) {
+	cause := errLeaseLost
+	if latest, err := db.SelectJobBySlug(slug); err == nil && latest.State == "CANCELLED" {
+		cause = errCancelled
+	}
+	log.Printf("worker %s stopping job %s: %v\n", workerID, slug, cause)
+	stop(slug, cause)
+}
+
+// Cancels the job and stops whichever worker is on it. Jobs that are already
+// done are returned untouched
+func Cancel(slug string) (*db.Job, error) {
+	job, err := db.CancelJob(slug)
 	if err != nil {
-		_ = os.Remove(vidPath)
-		if err := job.OhNo(err); err != nil {
-			log.Println(err)
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if job.State != "CANCELLED" {
+		return job, nil
+	}
+	// the worker cleans up after itself. If it's on another instance it finds out
+	// when it next renews its lease
+	if !stop(slug, errCancelled) && job.WorkerID == nil {
+		_ = os.RemoveAll(workDir(job))
+	}
+	broadcast(job)
+	return job, nil
+}
+
+// Where the job keeps its clips and reel until it's done, so an attempt that
+// gets cut short doesn't have to start over
+func workDir(job *db.Job) string {
+	return filepath.Join(config.WorkDir, job.Hash)
+}
+
+// Persists the job's new state and lets anyone watching the job know about it
+func setState(ctx context.Context, job *db.Job, state string) error {
+	if err := ctx.Err(); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	job.State = state
+	err := db.UpdateJob(job)
+	if errors.Is(err, db.ErrLeaseLost) {
+		// nothing was written, so there's nothing to tell anyone either
+		workerID := ""
+		if job.WorkerID != nil {
+			workerID = *job.WorkerID
+		}
+		stopLost(workerID, job.Slug)
+		return utils.ErrorWithTrace(err)
+	} else if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	broadcast(job)
+	return nil
+}
+
+// Sends the job back to the queue when the failure looks transient and it has
+// attempts left, otherwise it's an ERROR
+func ohNo(ctx context.Context, job *db.Job, e error) {
+	if ctx.Err() != nil {
+		// someone else has the job now, it's theirs to update
+		if errors.Is(context.Cause(ctx), errLeaseLost) {
+			return
 		}
+		// the process is going away, the job isn't at fault
+		if errors.Is(context.Cause(ctx), errInterrupted) {
+			if err := db.RequeueJob(job); err != nil {
+				log.Println(err)
+			}
+			broadcast(job)
+			return
+		}
+		// whatever went wrong was us pulling the plug, Cancel already recorded it
+		job.State = "CANCELLED"
 		return
 	}
-	if err := db.InsertVideo(db.NewVideo(title, desc, url, job.Id)); err != nil {
-		if err := job.OhNo(err); err != nil {
-			log.Println(err)
+	if utils.IsTransient(e) && job.Attempts < maxAttempts {
+		delay := backoff(job.Attempts)
+		log.Printf("job %s failed on attempt %d/%d, retrying in %s: %v\n", job.Slug, job.Attempts, maxAttempts, delay, e)
+		err := db.RetryJob(job, e, delay)
+		if err == nil {
+			broadcast(job)
+			return
 		}
+		log.Println(err)
 	}
-	job.State = "FINISHED"
-	if err := db.UpdateJob(job); err != nil {
+	if err := job.OhNo(e); err != nil {
 		log.Println(err)
-		if err := job.OhNo(err); err != nil {
-			log.Println(err)
-		}
+	}
+	broadcast(job)
+}
+
+const maxAttempts = 5
+const baseRetryDelay = 30 * time.Second
+const maxRetryDelay = 30 * time.Minute
+
+// Exponential backoff with jitter: half the delay is fixed, the other half random,
+// so jobs that failed together don't all come back at once
+func backoff(attempt int) time.Duration {
+	delay := baseRetryDelay << max(0, attempt-1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func broadcast(job *db.Job) {
+	events.Publish(*events.NewJobEvent(job.Slug, job.State, job.ErrorDetails, job.ProgressStage, job.ProgressCurrent, job.ProgressTotal))
+}
+
+const progressSaveInterval = time.Second
+
+// Tracks how far along the current stage a job is. Every step is published,
+// but the db only hears about it once a second and at the start and end of a stage
+type progress struct {
+	mu        sync.Mutex
+	job       *db.Job
+	lastSaved time.Time
+}
+
+func newProgress(job *db.Job) *progress {
+	return &progress{
+		job: job,
+	}
+}
+
+func (p *progress) start(stage string, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.job.ProgressStage = stage
+	p.job.ProgressCurrent = 0
+	p.job.ProgressTotal = total
+	p.flush()
+}
+
+func (p *progress) step() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.job.ProgressCurrent++
+	p.flush()
+}
+
+func (p *progress) set(current int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if current == p.job.ProgressCurrent {
+		return
+	}
+	p.job.ProgressCurrent = current
+	p.flush()
+}
+
+func (p *progress) flush() {
+	broadcast(p.job)
+	boundary := p.job.ProgressCurrent == 0 || p.job.ProgressCurrent >= p.job.ProgressTotal
+	if !boundary && time.Since(p.lastSaved) < progressSaveInterval {
 		return
 	}
+	p.lastSaved = time.Now()
+	if err := db.UpdateJobProgress(p.job); err != nil {
+		log.Println(err)
+	}
 }
 
-func makeTitle(season string, games []db.DatabaseGame, playerNames []string) string {
+// A team reel goes by the team's name instead of its whole roster
+func makeTitle(season string, games []db.DatabaseGame, playerNames []string, teamName string) string {
 	nameCharLimit := titleCharLimit/2 - 7
 	gameCharLimit := titleCharLimit/2 - 7
 
@@ -149,6 +482,9 @@ func makeTitle(season string, games []db.DatabaseGame, playerNames []string) str
 	}
 
 	namesList := strings.Join(formatted, ", ")
+	if teamName != "" {
+		namesList = teamName
+	}
 	if len(namesList) > nameCharLimit {
 		namesList = namesList[:nameCharLimit-3]
 		namesList += "..."
@@ -167,7 +503,7 @@ func makeTitle(season string, games []db.DatabaseGame, playerNames []string) str
 	return namesList + " | " + gamesList + " | " + season
 }
 
-func makeDescription(season string, games []db.DatabaseGame, playerNames []string) string {
+func makeDescription(season string, games []db.DatabaseGame, playerNames []string, teamName string, measures []nba.VideoDetailsAssetContextMeasure, dunksOnly bool, situation []string) string {
 	matchups := make([]string, 0, len(games))
 	for _, g := range games {
 		matchupString := fmt.Sprintf("%s %s", g.Matchup, g.GameDate)
@@ -175,8 +511,23 @@ func makeDescription(season string, games []db.DatabaseGame, playerNames []strin
 	}
 	matchupText := strings.Join(matchups, "\n")
 	nameText := strings.Join(playerNames, "\n")
+	labels := make([]string, 0, len(measures))
+	for _, m := range measures {
+		labels = append(labels, m.Label())
+	}
+	measureText := strings.Join(labels, "\n")
+	if dunksOnly {
+		measureText += "\n\nDunks Only"
+	}
 
-	desc := "Season: " + season + "\n\nPlayers:\n" + nameText + "\n\nGames:\n" + matchupText
+	desc := "Season: " + season
+	if teamName != "" {
+		desc += "\n\nTeam: " + teamName
+	}
+	desc += "\n\nPlayers:\n" + nameText + "\n\nGames:\n" + matchupText + "\n\nPlays:\n" + measureText
+	if len(situation) > 0 {
+		desc += "\n\nSituation:\n" + strings.Join(situation, "\n")
+	}
 	if len(desc) > descCharLimit {
 		desc = desc[:descCharLimit-3]
 		desc += "..."
@@ -195,171 +546,322 @@ func makeTags(season string, games []db.DatabaseGame, players []nba.CommonAllPla
 	return tags
 }
 
-func downloadAndConcat(urls []string) (string, error) {
-	tmpDir, err := os.MkdirTemp(os.TempDir(), "")
-	if err != nil {
-		return "", utils.ErrorWithTrace(err)
+// Picks the best available resolution for each asset and numbers the clips in
+// the order they happened, game by game
+func newClips(jobID int, assets []nba.VideoDetailsAssetEntry) []db.JobClip {
+	slices.SortStableFunc(assets, func(a, b nba.VideoDetailsAssetEntry) int {
+		if a.GameID == nil || b.GameID == nil || a.EventID == nil || b.EventID == nil {
+			return 0
+		}
+		if *a.GameID != *b.GameID {
+			return strings.Compare(*a.GameID, *b.GameID)
+		}
+		return int(*a.EventID - *b.EventID)
+	})
+
+	clips := make([]db.JobClip, 0, len(assets))
+	for _, a := range assets {
+		if a.GameID == nil || a.EventID == nil {
+			continue
+		}
+		var url, resolution string
+		if a.LargeUrl != nil {
+			url, resolution = *a.LargeUrl, "large"
+		} else if a.MedUrl != nil {
+			url, resolution = *a.MedUrl, "medium"
+		} else if a.SmallUrl != nil {
+			url, resolution = *a.SmallUrl, "small"
+		} else {
+			continue
+		}
+		description := ""
+		if a.Description != nil {
+			description = *a.Description
+		}
+		var period *int
+		if a.Period != nil {
+			p := int(*a.Period)
+			period = &p
+		}
+		clips = append(clips, *db.NewJobClip(jobID, *a.GameID, int(*a.EventID), period, description, url, resolution, len(clips)))
 	}
+	return clips
+}
 
+// Short, user facing version of why a clip didn't download
+func skipReason(err error) string {
+	var statusErr *utils.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return fmt.Sprintf("%d %s", statusErr.StatusCode, http.StatusText(statusErr.StatusCode))
+	}
+	for errors.Unwrap(err) != nil {
+		err = errors.Unwrap(err)
+	}
+	return err.Error()
+}
+
+// Downloads every clip into dir and stitches them together into output, marking
+// each clip DOWNLOADED or FAILED along the way. Clips that were downloaded by an
+// earlier attempt are kept as long as the file still matches its size and checksum.
+// Failed clips are left out as long as at least threshold of them made it,
+// otherwise the whole thing fails
+func downloadAndConcat(ctx context.Context, dir, output string, clips []db.JobClip, threshold float64, progress *progress) error {
 	wg := sync.WaitGroup{}
-	errChan := make(chan error, 1024)
+	mu := sync.Mutex{}
+	errs := []error{}
 
-	for i, u := range urls {
+	progress.start(db.ProgressClipsDownloaded, len(clips))
+	for i := range clips {
+		c := &clips[i]
+		fileName := clipFile(dir, c)
+		if c.DownloadStatus == "DOWNLOADED" && isIntact(fileName, c) {
+			progress.step()
+			continue
+		}
+		c.DownloadStatus = "PENDING"
+		c.ErrorDetails = nil
+		c.FileSize = nil
+		c.FileSHA256 = nil
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			fileName := fmt.Sprintf("%s/%04d.mp4", tmpDir, i)
-			if err := utils.CurlToFile(u, fileName); err != nil {
-				errChan <- utils.ErrorWithTrace(err)
+			if err := downloadClip(ctx, c, fileName); err != nil {
+				// don't let a half written clip end up in the concat
+				_ = os.Remove(fileName)
+				reason := skipReason(err)
+				c.DownloadStatus = "FAILED"
+				c.ErrorDetails = &reason
+				mu.Lock()
+				errs = append(errs, utils.ErrorWithTrace(err))
+				mu.Unlock()
+			} else {
+				c.DownloadStatus = "DOWNLOADED"
+				progress.step()
+			}
+			// a clip that failed because the job was cancelled didn't really fail
+			if ctx.Err() != nil {
+				return
+			}
+			if err := db.UpdateJobClipStatuses([]db.JobClip{*c}); err != nil {
+				log.Println(err)
 			}
 		}()
 	}
 
 	wg.Wait()
-	close(errChan)
+	if err := ctx.Err(); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
 
-	if len(errChan) > 0 {
-		errs := make([]error, 0, len(errChan))
-		for err := range errChan {
-			errs = append(errs, err)
+	if len(errs) > 0 {
+		succeeded := len(clips) - len(errs)
+		if succeeded == 0 || float64(succeeded)/float64(len(clips)) < threshold {
+			return utils.ErrorWithTrace(fmt.Errorf("only %d of %d clips downloaded: %w", succeeded, len(clips), errors.Join(errs...)))
 		}
-		_ = os.RemoveAll(tmpDir)
-		return "", utils.ErrorWithTrace(errors.Join(errs...))
+		log.Printf("skipping %d of %d clips\n", len(errs), len(clips))
 	}
 
-	vid, err := ffmpegConcat(tmpDir)
-	if err != nil {
-		return "", utils.ErrorWithTrace(err)
+	files := make([]string, 0, len(clips))
+	for i := range clips {
+		if clips[i].DownloadStatus == "DOWNLOADED" {
+			files = append(files, clipFile(dir, &clips[i]))
+		}
 	}
+	progress.start(db.ProgressConcat, 1)
+	if err := ffmpegConcat(ctx, dir, files, output); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	progress.step()
 
-	return vid, nil
+	return nil
 }
 
-func sortAssetURLs(assets *[]string) error {
-	re := regexp.MustCompile(`(?:https:\/\/videos.nba.com\/nba\/pbp\/media\/\d+\/\d+\/\d+\/)(\d+)\/(\d+)`)
-	errs := []error{}
-	slices.SortStableFunc(*assets, func(a, b string) int {
-		matchesA := re.FindStringSubmatch(a)
-		matchesB := re.FindStringSubmatch(b)
-
-		sortNumA := matchesA[1] + fmt.Sprintf("%03s", matchesA[2])
-		sortNumB := matchesB[1] + fmt.Sprintf("%03s", matchesB[2])
-
-		numA, err := strconv.Atoi(sortNumA)
-		if err != nil {
-			errs = append(errs, err)
-			return 0
-		}
-		numB, err := strconv.Atoi(sortNumB)
-		if err != nil {
-			errs = append(errs, err)
-			return 0
-		}
+func clipFile(dir string, clip *db.JobClip) string {
+	return filepath.Join(dir, fmt.Sprintf("%04d.mp4", clip.Position))
+}
 
-		return numA - numB
-	})
-	if len(errs) > 0 {
-		errors.Join(errs...)
+// Downloads the clip and records the size and checksum of what was downloaded
+func downloadClip(ctx context.Context, clip *db.JobClip, fileName string) error {
+	if err := utils.CurlToFile(ctx, clip.SourceURL, fileName); err != nil {
+		return utils.ErrorWithTrace(err)
 	}
+	size, sum, err := checksum(fileName)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	clip.FileSize = &size
+	clip.FileSHA256 = &sum
 	return nil
 }
 
-// ffmpeg is written in c and assembly language
-func ffmpegConcat(dir string) (string, error) {
-	files, err := os.ReadDir(dir)
+// True when the clip's file is exactly what was downloaded
+func isIntact(fileName string, clip *db.JobClip) bool {
+	if clip.FileSize == nil || clip.FileSHA256 == nil {
+		return false
+	}
+	info, err := os.Stat(fileName)
+	if err != nil || info.Size() != *clip.FileSize {
+		return false
+	}
+	_, sum, err := checksum(fileName)
+	return err == nil && sum == *clip.FileSHA256
+}
+
+func checksum(fileName string) (int64, string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return 0, "", utils.ErrorWithTrace(err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
 	if err != nil {
-		return "", utils.ErrorWithTrace(err)
+		return 0, "", utils.ErrorWithTrace(err)
 	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
 
-	listName := fmt.Sprintf("%s/files.txt", dir)
+// ffmpeg is written in c and assembly language. The output is written to a
+// partial file first and only renamed once ffmpeg is done with it
+func ffmpegConcat(ctx context.Context, dir string, files []string, output string) error {
+	listName := filepath.Join(dir, "files.txt")
 	list, err := os.Create(listName)
 	if err != nil {
-		return "", utils.ErrorWithTrace(err)
+		return utils.ErrorWithTrace(err)
 	}
 	defer list.Close()
 
 	for _, f := range files {
-		_, err := list.Write([]byte(fmt.Sprintf("file '%s'\n", f.Name())))
+		_, err := list.Write([]byte(fmt.Sprintf("file '%s'\n", f)))
 		if err != nil {
-			return "", utils.ErrorWithTrace(err)
+			return utils.ErrorWithTrace(err)
 		}
 	}
+	if err := list.Close(); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
 
-	timeString := fmt.Sprintf("%d%d", time.Now().Unix(), rand.Intn(math.MaxInt64))
-	sum := md5.Sum([]byte(timeString))
-	// home, err := os.UserHomeDir()
-	// if err != nil {
-	// 	return "", utils.ErrorWithTrace(err)
-	// }
-	outputFileName := os.TempDir() + "/" + fmt.Sprintf("%x", sum) + ".mp4"
-
-	args := []string{"-hide_banner", "-v", "fatal", "-f", "concat", "-safe", "0", "-vsync", "0", "-i", fmt.Sprintf("%s/files.txt", dir), "-c", "copy", outputFileName}
-	cmd := exec.Command("ffmpeg", args...)
+	partial := strings.TrimSuffix(output, ".mp4") + ".partial.mp4"
+	args := []string{"-hide_banner", "-v", "fatal", "-y", "-f", "concat", "-safe", "0", "-vsync", "0", "-i", listName, "-c", "copy", partial}
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 	cmd.Stdin, cmd.Stderr, cmd.Stdout = os.Stdin, os.Stderr, os.Stdout
 
 	if err := cmd.Run(); err != nil {
-		_ = os.RemoveAll(dir)
-		_ = os.Remove(outputFileName)
-		return "", utils.ErrorWithTrace(err)
+		_ = os.Remove(partial)
+		return utils.ErrorWithTrace(err)
+	}
+	if err := os.Rename(partial, output); err != nil {
+		_ = os.Remove(partial)
+		return utils.ErrorWithTrace(err)
 	}
 
-	return outputFileName, nil
-}
-
-var contextMeasures = []nba.VideoDetailsAssetContextMeasure{
-	nba.VideoDetailsAssetContextMeasures.FGA,
-	nba.VideoDetailsAssetContextMeasures.REB,
-	nba.VideoDetailsAssetContextMeasures.AST,
-	nba.VideoDetailsAssetContextMeasures.STL,
-	nba.VideoDetailsAssetContextMeasures.TOV,
-	nba.VideoDetailsAssetContextMeasures.BLK,
+	return nil
 }
 
-func getAssets(season string, gameIDs []string, playerIDs []string) ([]nba.VideoDetailsAssetEntry, error) {
+// refresh skips the cache and asks the NBA again
+func getAssets(ctx context.Context, season string, gameIDs []string, playerIDs []string, teamID int, contextMeasures []nba.VideoDetailsAssetContextMeasure, opts nba.VideoDetailsAssetOptions, window nba.VideoDetailsAssetWindow, refresh bool, progress *progress) ([]nba.VideoDetailsAssetEntry, error) {
 	if utils.IsInvalidSeason(season) {
 		return nil, utils.ErrorWithTrace(fmt.Errorf("invalid season provided :%s", season))
 	}
-	assetChan := make(chan nba.VideoDetailsAssetEntry, 1024)
-	errChan := make(chan error, 1024)
-	wg := sync.WaitGroup{}
+	// a window covers every game of a season type at once
+	seasonTypes := []string{}
+	if !window.IsZero() {
+		var err error
+		if seasonTypes, err = nba.GameIDsToSeasonTypeStrings(gameIDs); err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+	}
+	// a team is looked up as a whole with the team's version of each measure
+	if teamID != 0 {
+		playerIDs = []string{"0"}
+	}
 
-	for _, gid := range gameIDs {
-		for _, pid := range playerIDs {
-			for _, m := range contextMeasures {
-				time.Sleep(200 * time.Millisecond)
-				wg.Add(1)
-				go func() {
-					defer wg.Done()
-					assets, err := nba.VideoDetailsAsset(season, gid, pid, m)
-					if err != nil {
-						errChan <- utils.ErrorWithTrace(err)
+	// the cache is kept game by game and player by player, so window and team
+	// lookups go straight to the NBA
+	type lookup func() ([]nba.VideoDetailsAssetEntry, error)
+	lookups := []lookup{}
+	for _, pid := range playerIDs {
+		for _, m := range contextMeasures {
+			if teamID != 0 {
+				m = m.Team()
+			}
+			for _, seasonType := range seasonTypes {
+				lookups = append(lookups, func() ([]nba.VideoDetailsAssetEntry, error) {
+					return nba.VideoDetailsAssetInWindow(ctx, season, seasonType, pid, teamID, m, opts, window)
+				})
+			}
+			if !window.IsZero() {
+				continue
+			}
+			for _, gid := range gameIDs {
+				lookups = append(lookups, func() ([]nba.VideoDetailsAssetEntry, error) {
+					if teamID != 0 {
+						return nba.TeamVideoDetailsAsset(ctx, season, gid, teamID, m, opts)
 					}
-					for _, a := range assets {
-						assetChan <- a
+					// narrowed lookups aren't cached in the first place
+					if refresh && opts.IsZero() {
+						return cache.Refresh(ctx, season, gid, pid, m)
 					}
-				}()
+					return cache.VideoDetailsAsset(ctx, season, gid, pid, m, opts)
+				})
 			}
 		}
 	}
 
-	wg.Wait()
-	close(errChan)
-	close(assetChan)
+	// lookups can return any number of entries, so they're gathered up as they
+	// come back rather than sent down a channel that could fill up
+	var mu sync.Mutex
+	found := []nba.VideoDetailsAssetEntry{}
+	errs := []error{}
+	wg := sync.WaitGroup{}
 
-	if len(errChan) > 0 {
-		errs := []error{}
-		for e := range errChan {
-			errs = append(errs, e)
+	progress.start(db.ProgressAssetLookups, len(lookups))
+spawn:
+	for _, l := range lookups {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			errs = append(errs, utils.ErrorWithTrace(ctx.Err()))
+			mu.Unlock()
+			break spawn
+		case <-time.After(200 * time.Millisecond):
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assets, err := l()
+			progress.step()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, utils.ErrorWithTrace(err))
+				return
+			}
+			found = append(found, assets...)
+		}()
+	}
+
+	wg.Wait()
+	if len(errs) > 0 {
 		return nil, errors.Join(errs...)
 	}
 
-	assetMap := map[float64]nba.VideoDetailsAssetEntry{}
-	for a := range assetChan {
-		if a.EventID == nil {
+	// event ids start over every game, it takes both to pick out a play
+	type play struct {
+		gameID  string
+		eventID float64
+	}
+	assetMap := map[play]nba.VideoDetailsAssetEntry{}
+	for _, a := range found {
+		if a.GameID == nil || a.EventID == nil {
+			continue
+		}
+		// a window can take in a game the job doesn't cover, i.e. another
+		// player's on the day of its first game
+		if !window.IsZero() && !slices.Contains(gameIDs, *a.GameID) {
 			continue
 		}
-		assetMap[*a.EventID] = a
+		assetMap[play{*a.GameID, *a.EventID}] = a
 	}
 	assets := make([]nba.VideoDetailsAssetEntry, 0, len(assetMap))
 	for _, v := range assetMap {
@@ -369,64 +871,127 @@ func getAssets(season string, gameIDs []string, playerIDs []string) ([]nba.Video
 	return assets, nil
 }
 
-type Scheduler struct {
-	Id           int
-	MaxWorkers   int
+// Hands queued jobs out to a fixed pool of workers. It dispatches as soon as it's
+// notified of a new job or a worker frees up, and polls every PollInterval for
+// anything it wasn't told about, like retries whose backoff has run out
+type Dispatcher struct {
 	PollInterval time.Duration
 	Workers      []*Worker
+
+	mu     sync.Mutex
+	closed bool
+	wake   chan struct{}
+	quit   chan struct{}
+	wg     sync.WaitGroup
 }
 
-func NewScheduler(id int, maxWorkers int, pollInterval time.Duration) *Scheduler {
-	s := Scheduler{
-		Id:           id,
-		MaxWorkers:   maxWorkers,
+func NewDispatcher(workers int, pollInterval time.Duration, publisher publish.Publisher) *Dispatcher {
+	d := Dispatcher{
 		PollInterval: pollInterval,
-		Workers:      make([]*Worker, 0, maxWorkers),
+		Workers:      make([]*Worker, 0, workers),
+		wake:         make(chan struct{}, 1),
+		quit:         make(chan struct{}),
 	}
-	for i := range maxWorkers {
-		s.Workers = append(s.Workers, NewWorker(i))
+	for i := range workers {
+		d.Workers = append(d.Workers, NewWorker(i, publisher))
 	}
-	return &s
+	return &d
 }
 
-func (s *Scheduler) Start() {
-	ticker := time.NewTicker(s.PollInterval)
+// Runs until Shutdown is called
+func (d *Dispatcher) Start() {
+	ticker := time.NewTicker(d.PollInterval)
 	defer ticker.Stop()
-	for range ticker.C {
-		w := s.GetIdleWorker()
+	for {
+		d.dispatch()
+		select {
+		case <-d.quit:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Lets the dispatcher know there may be a job waiting. Never blocks
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) Status() []WorkerStatus {
+	statuses := make([]WorkerStatus, 0, len(d.Workers))
+	for _, w := range d.Workers {
+		statuses = append(statuses, w.Status())
+	}
+	return statuses
+}
+
+// Stops handing out jobs and waits for the ones in flight to finish. Whatever is
+// still running once ctx is done gets interrupted and put back in the queue,
+// its work directory lets it carry on where it left off after the restart
+func (d *Dispatcher) Shutdown(ctx context.Context) {
+	d.mu.Lock()
+	d.closed = true
+	close(d.quit)
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return
+	case <-ctx.Done():
+	}
+	for _, w := range d.Workers {
+		if status := w.Status(); !status.Idle {
+			log.Printf("worker %d interrupted, requeueing job %s\n", status.Id, status.JobSlug)
+			stop(status.JobSlug, errInterrupted)
+		}
+	}
+	<-drained
+}
+
+// Gives each idle worker a job until either runs out
+func (d *Dispatcher) dispatch() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for !d.closed {
+		w := d.idleWorker()
 		if w == nil {
-			continue
+			return
 		}
-		w.IsIdle = false
 
-		job, err := db.SelectJobForUpdate()
+		job, err := db.SelectJobForUpdate(w.Name, *config.JobLease)
 		if err != nil && strings.Contains(err.Error(), "QUEUE EMPTY") {
-			w.IsIdle = true
-			continue
+			return
 		} else if err != nil {
-			w.IsIdle = true
 			log.Println(utils.ErrorWithTrace(err))
-			continue
+			return
 		}
-		go w.DoYourJob(job)
+		w.assign(job)
+		broadcast(job)
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			w.DoYourJob(job)
+			w.assign(nil)
+			// there may be jobs that were waiting on a free worker
+			d.Notify()
+		}()
 	}
 }
 
-func (s *Scheduler) GetIdleWorker() *Worker {
-	for _, w := range s.Workers {
-		if w.IsIdle {
+func (d *Dispatcher) idleWorker() *Worker {
+	for _, w := range d.Workers {
+		if w.Status().Idle {
 			return w
 		}
 	}
 	return nil
 }
-
-func StalledJobsJanitory(duration time.Duration) {
-	ticker := time.NewTicker(duration)
-	defer ticker.Stop()
-	for range ticker.C {
-		if err := db.ResetStaleJobs(); err != nil {
-			log.Println(err)
-		}
-	}
-}
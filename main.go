@@ -1,26 +1,35 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
 	"dunkod/config"
 	"dunkod/db"
+	"dunkod/estimate"
+	"dunkod/events"
 	"dunkod/jobs"
 	"dunkod/nba"
+	"dunkod/publish"
 	"dunkod/scrape"
+	"dunkod/session"
 	"dunkod/utils"
 	"dunkod/youtube"
 
@@ -56,6 +65,9 @@ type State struct {
 	ValidSeasons []string
 	GameData     *GameData
 	PlayerData   *PlayerData
+	Teams        []db.Team
+	Measures     []MeasureOption
+	Situation    SituationOptions
 	Error        string
 }
 
@@ -65,6 +77,51 @@ func newState(season string, validSeasons []string, gameData *GameData, playerDa
 		ValidSeasons: validSeasons,
 		GameData:     gameData,
 		PlayerData:   playerData,
+		Measures:     newMeasureOptions(nba.DefaultContextMeasures),
+		Situation:    newSituationOptions(),
+	}
+}
+
+type MeasureOption struct {
+	Measure nba.VideoDetailsAssetContextMeasure
+	Label   string
+	Checked bool
+}
+
+func newMeasureOptions(checked []nba.VideoDetailsAssetContextMeasure) []MeasureOption {
+	options := make([]MeasureOption, 0, len(nba.SelectableContextMeasures))
+	for _, m := range nba.SelectableContextMeasures {
+		options = append(options, MeasureOption{
+			Measure: m,
+			Label:   m.Label(),
+			Checked: slices.Contains(checked, m),
+		})
+	}
+	return options
+}
+
+// Choices for the game situation part of the form, the first period is the
+// first quarter and the last is the first overtime
+type SituationOptions struct {
+	Periods      []PeriodOption
+	ClutchTimes  []nba.ClutchTime
+	AheadBehinds []nba.AheadBehind
+}
+
+type PeriodOption struct {
+	Value int
+	Label string
+}
+
+func newSituationOptions() SituationOptions {
+	periods := []PeriodOption{}
+	for p := 1; p <= 5; p++ {
+		periods = append(periods, PeriodOption{Value: p, Label: nba.PeriodLabel(p)})
+	}
+	return SituationOptions{
+		Periods:      periods,
+		ClutchTimes:  nba.ClutchTimes,
+		AheadBehinds: []nba.AheadBehind{nba.AheadBehinds.AheadOrBehind, nba.AheadBehinds.AheadOrTied, nba.AheadBehinds.BehindOrTied},
 	}
 }
 
@@ -92,40 +149,77 @@ func newPlayerData(selected, notSelected []db.PlayerSearchInfo) *PlayerData {
 	}
 }
 
+type EstimateState struct {
+	Estimate *estimate.Estimate
+	Error    string
+}
+
 type JobState struct {
-	Players []string
-	Games   []string
-	Job     *db.Job
-	Video   *db.Video
-	Error   string
+	Team      string
+	Players   []string
+	Games     []string
+	Measures  []string
+	Situation []string
+	Clips     []db.JobClip
+	Job       *db.Job
+	Video     *db.Video
+	Error     string
 }
 
 func newJobState(job *db.Job) *JobState {
 	return &JobState{
-		Job:     job,
-		Players: []string{},
-		Games:   []string{},
-		Error:   "",
+		Job:       job,
+		Players:   []string{},
+		Games:     []string{},
+		Measures:  []string{},
+		Situation: []string{},
+		Clips:     []db.JobClip{},
+		Error:     "",
 	}
 }
 
+// A job asked for in the visitor's session, for the reels page
+type Reel struct {
+	Job db.Job
+	// the team's name for a team's reel, which has no players
+	Team    string
+	Players []string
+	Games   []string
+	Video   *db.Video
+}
+
+type ReelsState struct {
+	Reels []Reel
+	Error string
+}
+
 var sigChan = make(chan os.Signal, 1)
 
-func init() {
+var publisher publish.Publisher
+var dispatcher *jobs.Dispatcher
+
+// Run from main rather than init so tests of this package don't parse flags,
+// migrate the database or start any daemons
+func setup() {
 	if err := config.LoadConfig(); err != nil {
 		panic(err)
 	}
+	if err := session.Init(); err != nil {
+		panic(err)
+	}
 	if err := db.SetupDatabase(); err != nil {
 		panic(err)
 	}
 	if err := db.RunMigrations(); err != nil {
 		panic(err)
 	}
-	signal.Notify(sigChan, syscall.SIGTERM, os.Interrupt, syscall.SIGINT)
-	go cleanup()
-	if err := youtube.InitService(); err != nil {
+	var err error
+	if publisher, err = publish.FromConfig(); err != nil {
 		panic(err)
 	}
+	dispatcher = jobs.NewDispatcher(*config.Workers, 15*time.Second, publisher)
+	signal.Notify(sigChan, syscall.SIGTERM, os.Interrupt, syscall.SIGINT)
+	go cleanup()
 	if *config.BigScrape {
 		if err := scrape.BigScrape(); err != nil {
 			panic(err)
@@ -133,14 +227,19 @@ func init() {
 		os.Exit(0)
 	}
 	go scrape.ScrapingDaemon(30 * time.Minute)
-	go youtube.ServiceJanitor(8 * time.Hour)
-	go jobs.StalledJobsJanitory(5 * time.Minute)
+	if publisher.Name() == youtube.PublisherName {
+		go youtube.ServiceJanitor(8 * time.Hour)
+	}
 	fmt.Println("The New York Knickerbockers are named after pants")
 }
 
 func cleanup() {
 	<-sigChan
-	fmt.Println("\nclosing database...")
+	fmt.Println("\nwaiting on running jobs...")
+	ctx, cancel := context.WithTimeout(context.Background(), *config.ShutdownTimeout)
+	dispatcher.Shutdown(ctx)
+	cancel()
+	fmt.Println("closing database...")
 	if err := db.Close(); err != nil {
 		panic(err)
 	}
@@ -148,16 +247,18 @@ func cleanup() {
 }
 
 func main() {
-	scheduler1 := jobs.NewScheduler(0, 2, time.Second*2)
-	scheduler2 := jobs.NewScheduler(0, 2, time.Second*2)
-	go scheduler1.Start()
-	go scheduler2.Start()
+	setup()
+	go dispatcher.Start()
 
 	e := echo.New()
 	e.Use(middleware.Logger())
 
 	e.Renderer = newTemplate()
+	e.HTTPErrorHandler = httpErrorHandler(e)
 	e.Static("/static", "static")
+	e.Match([]string{http.MethodGet, http.MethodHead}, publish.LocalURLPrefix+":name", serveVideo)
+
+	registerAPIRoutes(e)
 
 	e.GET("/", func(c echo.Context) error {
 		season := "2024-25"
@@ -174,6 +275,9 @@ func main() {
 		gameData := newGameData([]db.DatabaseGame{}, games)
 
 		state := newState(season, config.ValidSeasons, gameData, playerData)
+		if state.Teams, err = db.SelectTeams(); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
 
 		return c.Render(200, "index", state)
 	})
@@ -290,28 +394,47 @@ func main() {
 		return c.Render(200, "player-options", playerData)
 	})
 
-	e.POST("/", func(c echo.Context) error {
+	e.POST("/estimate", func(c echo.Context) error {
 		req := c.Request()
 		if err := req.ParseForm(); err != nil {
 			return utils.ErrorWithTrace(err)
 		}
 
-		season := req.FormValue("season")
-		gameIDs := req.Form["game"]
-		playerIDs := req.Form["player"]
-
-		assets, err := getAssets(season, gameIDs, playerIDs)
+		r, err := formJobRequest(req.Form)
+		if err != nil || (len(r.PlayerIDs) == 0 && r.TeamID == 0) || len(r.Measures) == 0 {
+			return c.Render(200, "estimate", nil)
+		}
+		resolved, err := resolveJobRequest(r)
 		if err != nil {
-			log.Println(utils.ErrorWithTrace(err))
-			return c.Render(200, "error", "unable to process request "+utils.Sad)
+			return c.Render(200, "estimate", &EstimateState{Error: userErrorMessage(err)})
 		}
-		if len(assets) == 0 {
-			return c.Render(200, "error", "no assets found "+utils.Sad)
+		est, err := estimateJob(resolved)
+		state := &EstimateState{Estimate: est}
+		if err != nil {
+			state.Error = userErrorMessage(err)
 		}
-		job := db.NewJob(playerIDs, gameIDs, season)
-		job, err = db.InsertJob(job)
+		return c.Render(200, "estimate", state)
+	})
+
+	e.POST("/", func(c echo.Context) error {
+		req := c.Request()
+		if err := req.ParseForm(); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+
+		r, err := formJobRequest(req.Form)
+		if err != nil {
+			return c.Render(200, "error", userErrorMessage(err))
+		}
+		job, err := createJob(r)
 		if err != nil {
-			return c.Render(200, "error", err.Error())
+			return c.Render(200, "error", userErrorMessage(err))
+		}
+		// losing track of the job shouldn't lose the job, it's still at its slug
+		if sessionID, err := ensureSession(c); err != nil {
+			log.Println(err)
+		} else if err := db.InsertSessionJob(sessionID, job.Id); err != nil {
+			log.Println(err)
 		}
 
 		redirect := fmt.Sprintf("/%s", job.Slug)
@@ -319,6 +442,23 @@ func main() {
 		return c.NoContent(200)
 	})
 
+	e.GET("/reels", func(c echo.Context) error {
+		state := ReelsState{Reels: []Reel{}}
+		sessionID, ok := currentSession(c)
+		if !ok {
+			return c.Render(200, "reels", state)
+		}
+		sessionJobs, err := db.SelectSessionJobs(sessionID)
+		if err != nil {
+			state.Error = userErrorMessage(err)
+			return c.Render(200, "reels", state)
+		}
+		if state.Reels, err = newReels(sessionJobs); err != nil {
+			state.Error = userErrorMessage(err)
+		}
+		return c.Render(200, "reels", state)
+	})
+
 	e.GET("/:slug", func(c echo.Context) error {
 		slug := c.Param("slug")
 		job, err := db.SelectJobBySlug(slug)
@@ -345,7 +485,7 @@ func main() {
 		jobState.Games = matchups
 
 		playerIds := []int{}
-		for _, idString := range strings.Split(job.Players, ",") {
+		for _, idString := range job.PlayerIDs() {
 			id, err := strconv.Atoi(idString)
 			if err != nil {
 				jobState.Error = err.Error()
@@ -367,6 +507,19 @@ func main() {
 			}
 		}
 		jobState.Players = playerNames
+		if job.TeamID != nil {
+			team, err := db.SelectTeamById(*job.TeamID)
+			if err != nil {
+				jobState.Error = err.Error()
+				return c.Render(200, "job", jobState)
+			}
+			jobState.Team = team.FullName()
+		}
+
+		for _, m := range job.ContextMeasures() {
+			jobState.Measures = append(jobState.Measures, nba.VideoDetailsAssetContextMeasure(m).Label())
+		}
+		jobState.Situation = job.AssetOptions().Labels()
 
 		if job.State == "FINISHED" {
 			video, err := db.SelectVideoByJobId(job.Id)
@@ -377,32 +530,91 @@ func main() {
 			jobState.Video = video
 		}
 
+		clips, err := db.SelectJobClips(job.Id)
+		if err != nil {
+			jobState.Error = err.Error()
+			return c.Render(200, "job", jobState)
+		}
+		jobState.Clips = clips
+
 		return c.Render(200, "job", jobState)
 	})
 
-	e.POST("/:slug/status/:state", func(c echo.Context) error {
+	e.POST("/:slug/cancel", func(c echo.Context) error {
+		if _, err := jobs.Cancel(c.Param("slug")); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.NoContent(404)
+			}
+			return utils.ErrorWithTrace(err)
+		}
+		// the events stream takes care of showing the new state
+		return c.NoContent(204)
+	})
+
+	e.GET("/:slug/events", func(c echo.Context) error {
 		slug := c.Param("slug")
-		state := c.Param("state")
 		job, err := db.SelectJobBySlug(slug)
-		redirect := fmt.Sprintf("/%s", slug)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				return c.NoContent(404)
-			} else {
-				c.Response().Header().Set("HX-Redirect", redirect)
-				return c.NoContent(200)
-
 			}
+			return utils.ErrorWithTrace(err)
 		}
-		if job.State == state {
-			return c.NoContent(204)
+
+		// subscribe before sending the current state so no transition slips through the gap
+		updates, unsubscribe := events.Subscribe(slug)
+		defer unsubscribe()
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set(echo.HeaderCacheControl, "no-cache")
+		res.Header().Set(echo.HeaderConnection, "keep-alive")
+		res.WriteHeader(200)
+
+		if done, err := sendJobState(c, job); err != nil || done {
+			return err
 		}
 
-		if job.State == "FINISHED" {
-			c.Response().Header().Set("HX-Redirect", redirect)
-			return c.NoContent(200)
+		// the janitor and other processes don't publish, so check in with the db every so often
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Request().Context().Done():
+				return nil
+			case update := <-updates:
+				// state changes are rare enough to go get the whole row, retries and
+				// cancellations touch more than the event carries
+				if update.State != job.State {
+					if latest, err := db.SelectJobBySlug(slug); err == nil {
+						job = latest
+						break
+					}
+				}
+				job.State = update.State
+				job.ErrorDetails = update.ErrorDetails
+				job.ProgressStage = update.ProgressStage
+				job.ProgressCurrent = update.ProgressCurrent
+				job.ProgressTotal = update.ProgressTotal
+			case <-ticker.C:
+				latest, err := db.SelectJobBySlug(slug)
+				if err != nil {
+					log.Println(utils.ErrorWithTrace(err))
+					continue
+				}
+				if latest.State == job.State && latest.ProgressStage == job.ProgressStage && latest.ProgressCurrent == job.ProgressCurrent {
+					if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+						return nil
+					}
+					res.Flush()
+					continue
+				}
+				job = latest
+			}
+			if done, err := sendJobState(c, job); err != nil || done {
+				return err
+			}
 		}
-		return c.Render(200, "state", job)
 	})
 
 	e.Logger.Fatal(e.Start(":8080"))
@@ -443,65 +655,472 @@ func filterPlayersByQuery(players []db.PlayerSearchInfo, query string) ([]db.Pla
 	return filtered, nil
 }
 
-var contextMeasures = []nba.VideoDetailsAssetContextMeasure{
-	nba.VideoDetailsAssetContextMeasures.FGA,
-	nba.VideoDetailsAssetContextMeasures.REB,
-	nba.VideoDetailsAssetContextMeasures.AST,
-	nba.VideoDetailsAssetContextMeasures.STL,
-	nba.VideoDetailsAssetContextMeasures.TOV,
-	nba.VideoDetailsAssetContextMeasures.BLK,
-}
-
-func getAssets(season string, gameIDs []string, playerIDs []string) ([]nba.VideoDetailsAssetEntry, error) {
-	if utils.IsInvalidSeason(season) {
-		return nil, utils.ErrorWithTrace(fmt.Errorf("invalid season provided: '%s' "+utils.Sad, season))
-	}
-	assetChan := make(chan nba.VideoDetailsAssetEntry, 1024)
-	errChan := make(chan error, 1024)
-	wg := sync.WaitGroup{}
-
-	for _, gid := range gameIDs {
-		for _, pid := range playerIDs {
-			for _, m := range contextMeasures {
-				time.Sleep(200 * time.Millisecond)
-				wg.Add(1)
-				go func() {
-					defer wg.Done()
-					assets, err := nba.VideoDetailsAsset(season, gid, pid, m)
-					if err != nil {
-						errChan <- utils.ErrorWithTrace(err)
-					}
-					for _, a := range assets {
-						assetChan <- a
-					}
-				}()
+// An error caused by the request itself, safe to show back to the user
+type UserError struct {
+	Message string
+}
+
+func (e *UserError) Error() string {
+	return e.Message
+}
+
+func newUserError(message string) *UserError {
+	return &UserError{Message: message + " " + utils.Sad}
+}
+
+// Logs anything that isn't a UserError and hides it behind a generic message
+func userErrorMessage(err error) string {
+	var userErr *UserError
+	if errors.As(err, &userErr) {
+		return userErr.Message
+	}
+	log.Println(err)
+	return "unable to process request " + utils.Sad
+}
+
+type JobRequest struct {
+	Season    string
+	GameIDs   []string
+	PlayerIDs []string
+	Measures  []string
+	DunksOnly bool
+	Situation nba.VideoDetailsAssetOptions
+	// only clutch time plays
+	Clutch bool
+	// with no games picked the players' whole season is used, narrowed down to
+	// games between the dates (YYYY-MM-DD, either may be blank) and then to the
+	// most recent LastNGames of those
+	DateFrom   string
+	DateTo     string
+	LastNGames int
+	// instead of players, all of the team's plays in the games
+	TeamID int
+}
+
+func (r JobRequest) hasWindow() bool {
+	return r.DateFrom != "" || r.DateTo != "" || r.LastNGames != 0
+}
+
+// What a job request comes down to once it's been checked
+type resolvedJobRequest struct {
+	GameIDs []string
+	// for a team, whoever played for it in the games. The team is looked up as
+	// a whole, they only go towards the estimate
+	PlayerIDs []string
+	TeamID    int
+	Measures  []nba.VideoDetailsAssetContextMeasure
+	Situation nba.VideoDetailsAssetOptions
+	// set when no games were picked, the games are then looked up by date in one go
+	Window nba.VideoDetailsAssetWindow
+}
+
+// Validates the request and fills in whatever it left for us to work out.
+// Problems with the request itself come back as a UserError
+func resolveJobRequest(r JobRequest) (*resolvedJobRequest, error) {
+	if utils.IsInvalidSeason(r.Season) {
+		return nil, newUserError(fmt.Sprintf("invalid season provided: '%s'", r.Season))
+	}
+	if len(r.PlayerIDs) == 0 && r.TeamID == 0 {
+		return nil, newUserError("pick at least one player or a team")
+	}
+	if len(r.PlayerIDs) > 0 && r.TeamID != 0 {
+		return nil, newUserError("pick players or a team, not both")
+	}
+	if len(r.Measures) == 0 {
+		return nil, newUserError("pick at least one kind of play")
+	}
+	measures, err := nba.ParseContextMeasures(r.Measures)
+	if err != nil {
+		log.Println(err)
+		return nil, newUserError("unsupported kind of play")
+	}
+	situation := r.Situation
+	if r.Clutch {
+		if !situation.IsZero() {
+			return nil, newUserError("clutch time already sets the game situation, pick one or the other")
+		}
+		situation = nba.ClutchPreset
+	}
+	if err := situation.Validate(); err != nil {
+		log.Println(err)
+		return nil, newUserError("unsupported game situation")
+	}
+	if len(r.GameIDs) > 0 && r.hasWindow() {
+		return nil, newUserError("pick games or a date range, not both")
+	}
+	if err := validateWindow(r.DateFrom, r.DateTo, r.LastNGames); err != nil {
+		return nil, err
+	}
+	who := "those players"
+	if r.TeamID != 0 {
+		team, err := db.SelectTeamById(r.TeamID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newUserError(fmt.Sprintf("unknown team %d", r.TeamID))
+		} else if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		who = "the " + team.FullName()
+	}
+	gameIDs := r.GameIDs
+	window := nba.VideoDetailsAssetWindow{}
+	if len(gameIDs) == 0 {
+		if r.TeamID != 0 {
+			gameIDs, err = db.SelectTeamGameIDs(r.Season, r.TeamID, r.DateFrom, r.DateTo, r.LastNGames)
+		} else {
+			gameIDs, err = db.SelectPlayedGameIDs(r.Season, r.PlayerIDs, r.DateFrom, r.DateTo, r.LastNGames)
+		}
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		if len(gameIDs) == 0 {
+			if r.hasWindow() {
+				return nil, newUserError(fmt.Sprintf("no games for %s in %s between those dates", who, r.Season))
 			}
+			return nil, newUserError(fmt.Sprintf("no games for %s in %s yet", who, r.Season))
+		}
+		// the window is pinned to the first and last of the games, so the job
+		// keeps to the games it was asked for and a new game makes for a new job.
+		// The NBA counts last N games per player and season type, so that's left
+		// to the games we picked here
+		games, err := db.SelectGamesById([]string{gameIDs[0], gameIDs[len(gameIDs)-1]})
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		if len(games) == 0 {
+			return nil, utils.ErrorWithTrace(fmt.Errorf("games %s and %s not found", gameIDs[0], gameIDs[len(gameIDs)-1]))
+		}
+		window = nba.VideoDetailsAssetWindow{DateFrom: games[0].GameDate, DateTo: games[0].GameDate}
+		for _, g := range games {
+			window.DateFrom = min(window.DateFrom, g.GameDate)
+			window.DateTo = max(window.DateTo, g.GameDate)
+		}
+	}
+	playerIDs := r.PlayerIDs
+	if r.TeamID != 0 {
+		if playerIDs, err = db.SelectTeamPlayerIDs(r.TeamID, gameIDs); err != nil {
+			return nil, utils.ErrorWithTrace(err)
 		}
+		if len(playerIDs) == 0 {
+			return nil, newUserError(fmt.Sprintf("nobody played for %s in those games", who))
+		}
+	}
+	return &resolvedJobRequest{
+		GameIDs:   gameIDs,
+		PlayerIDs: playerIDs,
+		TeamID:    r.TeamID,
+		Measures:  measures,
+		Situation: situation,
+		Window:    window,
+	}, nil
+}
+
+// Checks the dates are real and in order, blank ones leave that end open
+func validateWindow(dateFrom, dateTo string, lastN int) error {
+	if lastN < 0 {
+		return newUserError("last games has to be a positive number")
+	}
+	var from, to time.Time
+	for _, d := range []struct {
+		raw  string
+		dest *time.Time
+	}{{dateFrom, &from}, {dateTo, &to}} {
+		if d.raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, d.raw)
+		if err != nil {
+			return newUserError(fmt.Sprintf("'%s' isn't a date, use YYYY-MM-DD", d.raw))
+		}
+		*d.dest = t
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return newUserError("the end date is before the start date")
+	}
+	return nil
+}
+
+// Validates the request and queues it up, finding the clips is up to the worker.
+// Shared by the htmx form and the json api
+func createJob(r JobRequest) (*db.Job, error) {
+	resolved, err := resolveJobRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := estimateJob(resolved); err != nil {
+		return nil, err
 	}
 
-	wg.Wait()
-	close(errChan)
-	close(assetChan)
+	measureStrings := make([]string, 0, len(resolved.Measures))
+	for _, m := range resolved.Measures {
+		measureStrings = append(measureStrings, string(m))
+	}
+	playerIDs := resolved.PlayerIDs
+	if resolved.TeamID != 0 {
+		playerIDs = []string{}
+	}
+	job := db.NewJob(playerIDs, resolved.GameIDs, r.Season, measureStrings)
+	job.DunksOnly = r.DunksOnly
+	if resolved.TeamID != 0 {
+		job.TeamID = &resolved.TeamID
+	}
+	job.SetAssetOptions(resolved.Situation)
+	job.SetWindow(resolved.Window)
+	job, err = db.InsertJob(job)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	dispatcher.Notify()
+	return job, nil
+}
 
-	if len(errChan) > 0 {
-		errs := []error{}
-		for e := range errChan {
-			errs = append(errs, e)
+// Reads a job request out of the htmx form
+func formJobRequest(form url.Values) (JobRequest, error) {
+	situation, err := parseSituation(form)
+	if err != nil {
+		return JobRequest{}, err
+	}
+	lastNGames, teamID := 0, 0
+	ints := []struct {
+		field string
+		dest  *int
+	}{
+		{"last-n-games", &lastNGames},
+		{"team", &teamID},
+	}
+	for _, i := range ints {
+		raw := strings.TrimSpace(form.Get(i.field))
+		if raw == "" {
+			continue
+		}
+		if *i.dest, err = strconv.Atoi(raw); err != nil {
+			return JobRequest{}, newUserError(fmt.Sprintf("'%s' isn't a number", raw))
 		}
-		return nil, errors.Join(errs...)
 	}
+	return JobRequest{
+		Season:     form.Get("season"),
+		GameIDs:    form["game"],
+		PlayerIDs:  form["player"],
+		Measures:   form["measure"],
+		DunksOnly:  form.Get("dunks-only") == "on",
+		Situation:  situation,
+		Clutch:     form.Get("clutch") == "on",
+		DateFrom:   strings.TrimSpace(form.Get("date-from")),
+		DateTo:     strings.TrimSpace(form.Get("date-to")),
+		LastNGames: lastNGames,
+		TeamID:     teamID,
+	}, nil
+}
 
-	assetMap := map[float64]nba.VideoDetailsAssetEntry{}
-	for a := range assetChan {
-		if a.EventID == nil {
+// Reads the game situation fields of the form, blank fields leave that part of the
+// situation open. Ahead or behind always has a value so it only counts with a point diff
+func parseSituation(form url.Values) (nba.VideoDetailsAssetOptions, error) {
+	opts := nba.VideoDetailsAssetOptions{
+		ClutchTime: nba.ClutchTime(form.Get("clutch-time")),
+	}
+	ints := []struct {
+		field string
+		dest  *int
+	}{
+		{"period", &opts.Period},
+		{"start-period", &opts.StartPeriod},
+		{"end-period", &opts.EndPeriod},
+		{"point-diff", &opts.PointDiff},
+	}
+	for _, i := range ints {
+		raw := strings.TrimSpace(form.Get(i.field))
+		if raw == "" {
 			continue
 		}
-		assetMap[*a.EventID] = a
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return opts, newUserError(fmt.Sprintf("'%s' isn't a number", raw))
+		}
+		*i.dest = n
+	}
+	if opts.PointDiff != 0 {
+		opts.AheadBehind = nba.AheadBehind(form.Get("ahead-behind"))
+	}
+	return opts, nil
+}
+
+// Estimates how big the job would be and turns it away with a UserError when
+// it's over the configured limits. The estimate is returned either way. Box
+// scores can't say how many plays happened in a game situation, so narrowed
+// jobs are held to the clip limit by the worker once it has found the plays
+func estimateJob(r *resolvedJobRequest) (*estimate.Estimate, error) {
+	est, err := estimate.ForRequest(r.GameIDs, r.PlayerIDs, r.TeamID, r.Measures, r.Situation, r.Window)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	if est.Lookups > *config.MaxLookups {
+		return est, newUserError(fmt.Sprintf("that's %d lookups (players x plays, and x games when games are picked) and the most one reel can do is %d, pick fewer", est.Lookups, *config.MaxLookups))
+	}
+	if est.Clips > *config.MaxClips && !est.Narrowed {
+		return est, newUserError(fmt.Sprintf("that's about %d clips and the most one reel can have is %d, pick fewer", est.Clips, *config.MaxClips))
 	}
-	assets := make([]nba.VideoDetailsAssetEntry, 0, len(assetMap))
-	for _, v := range assetMap {
-		assets = append(assets, v)
+	return est, nil
+}
+
+// Returns the visitor's session id if they have a validly signed cookie
+func currentSession(c echo.Context) (string, bool) {
+	cookie, err := c.Cookie(session.CookieName)
+	if err != nil {
+		return "", false
+	}
+	return session.Verify(cookie.Value)
+}
+
+// Returns the visitor's session id, starting a new session if they don't have one
+func ensureSession(c echo.Context) (string, error) {
+	if id, ok := currentSession(c); ok {
+		return id, nil
+	}
+	id, err := session.NewID()
+	if err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     session.CookieName,
+		Value:    session.Sign(id),
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		Secure:   *config.ProdFlag,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id, nil
+}
+
+// Resolves the players, games and videos for a list of jobs in one go rather than per job
+func newReels(jobList []db.Job) ([]Reel, error) {
+	reels := make([]Reel, 0, len(jobList))
+	if len(jobList) == 0 {
+		return reels, nil
+	}
+
+	gameIDs, playerIDs, finishedIDs := []string{}, []string{}, []int{}
+	for _, j := range jobList {
+		gameIDs = append(gameIDs, strings.Split(j.Games, ",")...)
+		playerIDs = append(playerIDs, strings.Split(j.Players, ",")...)
+		if j.State == "FINISHED" {
+			finishedIDs = append(finishedIDs, j.Id)
+		}
+	}
+	slices.Sort(gameIDs)
+	gameIDs = slices.Compact(gameIDs)
+
+	games, err := db.SelectGamesById(gameIDs)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	matchups := make(map[string]string, len(games))
+	for _, g := range games {
+		matchups[g.ID] = fmt.Sprintf("%s %s", g.Matchup, g.GameDate)
+	}
+	players, err := db.SelectPlayersById(playerIDs)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	names := make(map[string]string, len(players))
+	for _, p := range players {
+		names[strconv.Itoa(p.Id)] = p.Name
+	}
+	teams, err := db.SelectTeams()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	teamNames := make(map[int]string, len(teams))
+	for _, t := range teams {
+		teamNames[t.ID] = t.FullName()
+	}
+	videos := map[int]*db.Video{}
+	if len(finishedIDs) > 0 {
+		videoList, err := db.SelectVideosByJobIds(finishedIDs)
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		for i := range videoList {
+			videos[videoList[i].JobId] = &videoList[i]
+		}
+	}
+
+	for _, j := range jobList {
+		reel := Reel{Job: j, Players: []string{}, Games: []string{}, Video: videos[j.Id]}
+		if j.TeamID != nil {
+			reel.Team = teamNames[*j.TeamID]
+		}
+		for _, id := range strings.Split(j.Games, ",") {
+			reel.Games = append(reel.Games, matchups[id])
+		}
+		for _, id := range strings.Split(j.Players, ",") {
+			if name, ok := names[id]; ok {
+				reel.Players = append(reel.Players, name)
+			}
+		}
+		reels = append(reels, reel)
+	}
+	return reels, nil
+}
+
+// Writes the rendered state block as an sse event, followed by the closing events once the job is done
+func sendJobState(c echo.Context, job *db.Job) (bool, error) {
+	buf := bytes.Buffer{}
+	if err := c.Echo().Renderer.Render(&buf, "state", job, c); err != nil {
+		return true, utils.ErrorWithTrace(err)
+	}
+	if err := writeEvent(c.Response(), "state", buf.String()); err != nil {
+		return true, nil
+	}
+	if !job.IsDone() {
+		return false, nil
+	}
+	if job.State == "FINISHED" {
+		if err := writeEvent(c.Response(), "finished", job.Slug); err != nil {
+			return true, nil
+		}
+	}
+	_ = writeEvent(c.Response(), "done", job.Slug)
+	return true, nil
+}
+
+func writeEvent(res *echo.Response, event, data string) error {
+	msg := "event: " + event + "\n"
+	for _, line := range strings.Split(data, "\n") {
+		msg += "data: " + line + "\n"
+	}
+	if _, err := fmt.Fprint(res, msg+"\n"); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// Streams a locally published video. http.ServeContent takes care of Range and
+// If-Range for seeking and of the conditional headers once the ETag is set
+func serveVideo(c echo.Context) error {
+	name := c.Param("name")
+	if name != filepath.Base(name) || filepath.Ext(name) != ".mp4" {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	file, err := os.Open(filepath.Join(config.VideosDir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return utils.ErrorWithTrace(err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
 	}
 
-	return assets, nil
+	// published videos are never rewritten, so size and mod time are enough to identify one
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "video/mp4")
+	header.Set("ETag", fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	header.Set(echo.HeaderCacheControl, "public, max-age=86400")
+	http.ServeContent(c.Response(), c.Request(), name, info.ModTime(), file)
+	return nil
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		dateFrom string
+		dateTo   string
+		lastN    int
+		wantErr  bool
+	}{
+		{"nothing", "", "", 0, false},
+		{"last games", "", "", 10, false},
+		{"negative last games", "", "", -1, true},
+		{"date range", "2024-11-01", "2024-12-31", 0, false},
+		{"single day", "2024-12-25", "2024-12-25", 0, false},
+		{"open start", "", "2024-12-31", 0, false},
+		{"open end", "2024-11-01", "", 0, false},
+		{"backwards", "2024-12-31", "2024-11-01", 0, true},
+		{"not a date", "last week", "", 0, true},
+		{"us format", "", "12/31/2024", 0, true},
+		{"no such day", "2024-02-30", "", 0, true},
+	}
+	for _, tt := range tests {
+		err := validateWindow(tt.dateFrom, tt.dateTo, tt.lastN)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateWindow(%q, %q, %d) = %v, want error %v", tt.name, tt.dateFrom, tt.dateTo, tt.lastN, err, tt.wantErr)
+		}
+		var userErr *UserError
+		if err != nil && !errors.As(err, &userErr) {
+			t.Errorf("%s: validateWindow(%q, %q, %d) = %v, want a UserError", tt.name, tt.dateFrom, tt.dateTo, tt.lastN, err)
+		}
+	}
+}
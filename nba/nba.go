@@ -8,6 +8,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -561,11 +563,45 @@ type VideoDetailsAssetEntry struct {
 	Year        *float64
 	Month       *string
 	Day         *string
+	Period      *float64
 	Description *string
 	Uuid        *string
 	LargeUrl    *string
 	MedUrl      *string
 	SmallUrl    *string
+	IsDunk      bool
+}
+
+// Matches every flavor of dunk the play-by-play writes up, i.e.
+//
+//	"Gobert 1' Alley Oop Dunk (2 PTS) (Conley 4 AST)"
+//	"Williamson 1' Tip Dunk Shot (10 PTS)"
+//	"Jordan Slam Dunk Shot (24 PTS)"
+var dunkRegex = regexp.MustCompile(`(?i)\bdunk\b`)
+
+// Missed attempts, blocked ones included, lead with MISS, i.e.
+//
+//	"MISS Adebayo 2' Putback Dunk"
+//	"MISS Zion 1' Dunk    Gobert BLOCK (2 BLK)"
+var missRegex = regexp.MustCompile(`^\s*MISS\b`)
+
+// Only made dunks count, a dunks only reel of bricks and rejections isn't the idea
+func IsDunkDescription(description *string) bool {
+	if description == nil {
+		return false
+	}
+	return dunkRegex.MatchString(*description) && !missRegex.MatchString(*description)
+}
+
+// Removes every entry that isn't a dunk
+func FilterDunks(entries []VideoDetailsAssetEntry) []VideoDetailsAssetEntry {
+	dunks := make([]VideoDetailsAssetEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDunk {
+			dunks = append(dunks, e)
+		}
+	}
+	return dunks
 }
 
 type VideoDetailsAssetResp struct {
@@ -775,13 +811,314 @@ var VideoDetailsAssetContextMeasures = struct {
 	PTS:                "PTS",
 }
 
-func VideoDetailsAsset(season, gameID, playerID string, contextMeasure VideoDetailsAssetContextMeasure) ([]VideoDetailsAssetEntry, error) {
-	seasonType, err := gameIDToSeasonTypeString(gameID)
+// Context measures a user is allowed to build a reel out of, in the order they are shown on the form
+var SelectableContextMeasures = []VideoDetailsAssetContextMeasure{
+	VideoDetailsAssetContextMeasures.FGM,
+	VideoDetailsAssetContextMeasures.FGA,
+	VideoDetailsAssetContextMeasures.FG3M,
+	VideoDetailsAssetContextMeasures.FG3A,
+	VideoDetailsAssetContextMeasures.FTM,
+	VideoDetailsAssetContextMeasures.OREB,
+	VideoDetailsAssetContextMeasures.DREB,
+	VideoDetailsAssetContextMeasures.REB,
+	VideoDetailsAssetContextMeasures.AST,
+	VideoDetailsAssetContextMeasures.STL,
+	VideoDetailsAssetContextMeasures.BLK,
+	VideoDetailsAssetContextMeasures.TOV,
+	VideoDetailsAssetContextMeasures.PF,
+}
+
+// Context measures used when a user doesn't pick any
+var DefaultContextMeasures = []VideoDetailsAssetContextMeasure{
+	VideoDetailsAssetContextMeasures.FGA,
+	VideoDetailsAssetContextMeasures.REB,
+	VideoDetailsAssetContextMeasures.AST,
+	VideoDetailsAssetContextMeasures.STL,
+	VideoDetailsAssetContextMeasures.TOV,
+	VideoDetailsAssetContextMeasures.BLK,
+}
+
+var contextMeasureLabels = map[VideoDetailsAssetContextMeasure]string{
+	VideoDetailsAssetContextMeasures.FGM:  "Made Shots",
+	VideoDetailsAssetContextMeasures.FGA:  "Shot Attempts",
+	VideoDetailsAssetContextMeasures.FG3M: "Made Threes",
+	VideoDetailsAssetContextMeasures.FG3A: "Three Point Attempts",
+	VideoDetailsAssetContextMeasures.FTM:  "Made Free Throws",
+	VideoDetailsAssetContextMeasures.OREB: "Offensive Rebounds",
+	VideoDetailsAssetContextMeasures.DREB: "Defensive Rebounds",
+	VideoDetailsAssetContextMeasures.REB:  "Rebounds",
+	VideoDetailsAssetContextMeasures.AST:  "Assists",
+	VideoDetailsAssetContextMeasures.STL:  "Steals",
+	VideoDetailsAssetContextMeasures.BLK:  "Blocks",
+	VideoDetailsAssetContextMeasures.TOV:  "Turnovers",
+	VideoDetailsAssetContextMeasures.PF:   "Fouls",
+}
+
+// Human friendly name for the measure, falls back to the raw measure string
+func (m VideoDetailsAssetContextMeasure) Label() string {
+	if label, exists := contextMeasureLabels[m]; exists {
+		return label
+	}
+	return string(m)
+}
+
+// The team's version of the measure, i.e. TM_FGM for FGM, which every
+// selectable measure has
+func (m VideoDetailsAssetContextMeasure) Team() VideoDetailsAssetContextMeasure {
+	return VideoDetailsAssetContextMeasure("TM_" + string(m))
+}
+
+func (m VideoDetailsAssetContextMeasure) IsSelectable() bool {
+	return slices.Contains(SelectableContextMeasures, m)
+}
+
+// Validates raw measure strings (i.e. from a form) and converts them to context measures
+func ParseContextMeasures(raw []string) ([]VideoDetailsAssetContextMeasure, error) {
+	measures := make([]VideoDetailsAssetContextMeasure, 0, len(raw))
+	for _, r := range raw {
+		m := VideoDetailsAssetContextMeasure(strings.ToUpper(strings.TrimSpace(r)))
+		if !m.IsSelectable() {
+			return nil, utils.ErrorWithTrace(fmt.Errorf("unsupported context measure: '%s' "+utils.Sad, r))
+		}
+		if slices.Contains(measures, m) {
+			continue
+		}
+		measures = append(measures, m)
+	}
+	return measures, nil
+}
+
+// Stretch of the end of each period, as the NBA names them
+type ClutchTime string
+
+var ClutchTimes = []ClutchTime{
+	"Last 5 Minutes",
+	"Last 4 Minutes",
+	"Last 3 Minutes",
+	"Last 2 Minutes",
+	"Last 1 Minute",
+	"Last 30 Seconds",
+	"Last 10 Seconds",
+}
+
+// Which side of PointDiff the player's team has to be on
+type AheadBehind string
+
+var AheadBehinds = struct {
+	AheadOrBehind AheadBehind
+	AheadOrTied   AheadBehind
+	BehindOrTied  AheadBehind
+}{
+	AheadOrBehind: "Ahead or Behind",
+	AheadOrTied:   "Ahead or Tied",
+	BehindOrTied:  "Behind or Tied",
+}
+
+// 4 quarters and 6 overtimes ought to be enough for anybody
+const MaxPeriod = 10
+
+// Narrows VideoDetailsAsset down to plays from a game situation, i.e. the 4th
+// quarter, last 5 minutes, within 5 points. The zero value doesn't narrow anything
+type VideoDetailsAssetOptions struct {
+	ClutchTime ClutchTime
+	// the score has to be within PointDiff, AheadBehind says on which side of it
+	AheadBehind AheadBehind
+	PointDiff   int
+	// a single period, overtimes count on from 5. 0 for every period
+	Period int
+	// a range of periods, 0 leaves that end of it open
+	StartPeriod int
+	EndPeriod   int
+}
+
+// The NBA's own definition of clutch time: the last 5 minutes of the 4th quarter
+// or overtime with the score within 5 points
+var ClutchPreset = VideoDetailsAssetOptions{
+	ClutchTime:  "Last 5 Minutes",
+	AheadBehind: AheadBehinds.AheadOrBehind,
+	PointDiff:   5,
+	StartPeriod: 4,
+}
+
+func (o VideoDetailsAssetOptions) IsZero() bool {
+	return o == VideoDetailsAssetOptions{}
+}
+
+// Whether the options leave out any plays at all. A period range that starts
+// at the 1st and runs through the last possible period is the same as no range
+func (o VideoDetailsAssetOptions) Narrows() bool {
+	return o.ClutchTime != "" || o.PointDiff != 0 || o.Period != 0 ||
+		o.StartPeriod > 1 || (o.EndPeriod != 0 && o.EndPeriod < MaxPeriod)
+}
+
+func (o VideoDetailsAssetOptions) IsClutch() bool {
+	return o == ClutchPreset
+}
+
+func (o VideoDetailsAssetOptions) Validate() error {
+	if o.ClutchTime != "" && !slices.Contains(ClutchTimes, o.ClutchTime) {
+		return utils.ErrorWithTrace(fmt.Errorf("unsupported clutch time: '%s' "+utils.Sad, o.ClutchTime))
+	}
+	switch o.AheadBehind {
+	case "", AheadBehinds.AheadOrBehind, AheadBehinds.AheadOrTied, AheadBehinds.BehindOrTied:
+	default:
+		return utils.ErrorWithTrace(fmt.Errorf("unsupported ahead or behind: '%s' "+utils.Sad, o.AheadBehind))
+	}
+	if o.PointDiff < 0 {
+		return utils.ErrorWithTrace(fmt.Errorf("point diff can't be negative, got %d "+utils.Sad, o.PointDiff))
+	}
+	if (o.PointDiff == 0) != (o.AheadBehind == "") {
+		return utils.ErrorWithTrace(fmt.Errorf("point diff and ahead or behind only work together " + utils.Sad))
+	}
+	for _, p := range []int{o.Period, o.StartPeriod, o.EndPeriod} {
+		if p < 0 || p > MaxPeriod {
+			return utils.ErrorWithTrace(fmt.Errorf("period must be between 1 and %d, or 0 for any, got %d "+utils.Sad, MaxPeriod, p))
+		}
+	}
+	if o.Period != 0 && (o.StartPeriod != 0 || o.EndPeriod != 0) {
+		return utils.ErrorWithTrace(fmt.Errorf("period %d can't be combined with a range of periods "+utils.Sad, o.Period))
+	}
+	if o.StartPeriod != 0 && o.EndPeriod != 0 && o.StartPeriod > o.EndPeriod {
+		return utils.ErrorWithTrace(fmt.Errorf("start period %d is after end period %d "+utils.Sad, o.StartPeriod, o.EndPeriod))
+	}
+	return nil
+}
+
+// Stable string form of the options for telling jobs apart, "" for the zero value.
+// Only the options that are set show up so adding new ones doesn't change old keys
+func (o VideoDetailsAssetOptions) Key() string {
+	parts := []string{}
+	if o.ClutchTime != "" {
+		parts = append(parts, "clutch="+string(o.ClutchTime))
+	}
+	if o.AheadBehind != "" {
+		parts = append(parts, fmt.Sprintf("diff=%s %d", o.AheadBehind, o.PointDiff))
+	}
+	if o.Period != 0 {
+		parts = append(parts, fmt.Sprintf("period=%d", o.Period))
+	}
+	if o.StartPeriod != 0 || o.EndPeriod != 0 {
+		parts = append(parts, fmt.Sprintf("periods=%d-%d", o.StartPeriod, o.EndPeriod))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Human friendly description of each option that is set, i.e. "4th Quarter"
+func (o VideoDetailsAssetOptions) Labels() []string {
+	if o.IsClutch() {
+		return []string{"Clutch Time 🧊", "Last 5 Minutes of the 4th or OT", "Within 5 Points"}
+	}
+	labels := []string{}
+	if o.Period != 0 {
+		labels = append(labels, PeriodLabel(o.Period))
+	}
+	switch {
+	case o.StartPeriod != 0 && o.EndPeriod != 0:
+		labels = append(labels, PeriodLabel(o.StartPeriod)+" to "+PeriodLabel(o.EndPeriod))
+	case o.StartPeriod != 0:
+		labels = append(labels, PeriodLabel(o.StartPeriod)+" on")
+	case o.EndPeriod != 0:
+		labels = append(labels, "Up to "+PeriodLabel(o.EndPeriod))
+	}
+	if o.ClutchTime != "" {
+		labels = append(labels, string(o.ClutchTime))
+	}
+	switch o.AheadBehind {
+	case AheadBehinds.AheadOrBehind:
+		labels = append(labels, fmt.Sprintf("Within %d Points", o.PointDiff))
+	case AheadBehinds.AheadOrTied:
+		labels = append(labels, fmt.Sprintf("Up by %d or Fewer, or Tied", o.PointDiff))
+	case AheadBehinds.BehindOrTied:
+		labels = append(labels, fmt.Sprintf("Down by %d or Fewer, or Tied", o.PointDiff))
+	}
+	return labels
+}
+
+// Quarter or overtime name for the period, i.e. "4th Quarter" or "2OT"
+func PeriodLabel(p int) string {
+	switch p {
+	case 1:
+		return "1st Quarter"
+	case 2:
+		return "2nd Quarter"
+	case 3:
+		return "3rd Quarter"
+	case 4:
+		return "4th Quarter"
+	case 5:
+		return "OT"
+	default:
+		return fmt.Sprintf("%dOT", p-4)
+	}
+}
+
+// Picks the games for a lookup by date rather than by id, so one lookup covers
+// a player's whole season or any stretch of it. Dates are YYYY-MM-DD and a blank
+// one leaves that end open
+type VideoDetailsAssetWindow struct {
+	DateFrom string
+	DateTo   string
+}
+
+func (w VideoDetailsAssetWindow) IsZero() bool {
+	return w == VideoDetailsAssetWindow{}
+}
+
+// Stable string form of the window for telling jobs apart, "" for the zero value
+func (w VideoDetailsAssetWindow) Key() string {
+	if w.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("window=%s..%s", w.DateFrom, w.DateTo)
+}
+
+// Clips of the player's plays for the measure in the game, narrowed down to the situation in opts
+func VideoDetailsAsset(ctx context.Context, season, gameID, playerID string, contextMeasure VideoDetailsAssetContextMeasure, opts VideoDetailsAssetOptions) ([]VideoDetailsAssetEntry, error) {
+	seasonType, err := GameIDToSeasonTypeString(gameID)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	url := fmt.Sprintf("https://stats.nba.com/stats/videodetailsasset?AheadBehind=&ClutchTime=&ContextFilter=&DateFrom=&DateTo=&EndPeriod=&EndRange=&GameSegment=&LastNGames=0&LeagueID=&Location=&Month=0&OpponentTeamID=0&Outcome=&Period=0&PointDiff=&Position=&RangeType=&RookieYear=&SeasonSegment=&StartPeriod=&StartRange=&TeamID=0&VsConference=&VsDivision=&ContextMeasure=%s&GameID=%s&PlayerID=%s&Season=%s&SeasonType=%s", contextMeasure, gameID, playerID, season, seasonType)
-	body, err := curl(url)
+	return videoDetailsAsset(ctx, season, seasonType, gameID, playerID, 0, contextMeasure, opts, VideoDetailsAssetWindow{})
+}
+
+// Clips of the whole team's plays in the game, contextMeasure has to be one of
+// the TM_ measures
+func TeamVideoDetailsAsset(ctx context.Context, season, gameID string, teamID int, contextMeasure VideoDetailsAssetContextMeasure, opts VideoDetailsAssetOptions) ([]VideoDetailsAssetEntry, error) {
+	seasonType, err := GameIDToSeasonTypeString(gameID)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return videoDetailsAsset(ctx, season, seasonType, gameID, "0", teamID, contextMeasure, opts, VideoDetailsAssetWindow{})
+}
+
+// Clips of the player's plays for the measure in every game of the season type
+// that falls in the window, so there's no need to look them up game by game.
+// For a team's plays instead the player is "0" and the measure a TM_ one
+func VideoDetailsAssetInWindow(ctx context.Context, season, seasonType, playerID string, teamID int, contextMeasure VideoDetailsAssetContextMeasure, opts VideoDetailsAssetOptions, window VideoDetailsAssetWindow) ([]VideoDetailsAssetEntry, error) {
+	return videoDetailsAsset(ctx, season, seasonType, "", playerID, teamID, contextMeasure, opts, window)
+}
+
+func videoDetailsAsset(ctx context.Context, season, seasonType, gameID, playerID string, teamID int, contextMeasure VideoDetailsAssetContextMeasure, opts VideoDetailsAssetOptions, window VideoDetailsAssetWindow) ([]VideoDetailsAssetEntry, error) {
+	// the api wants its dates as MM/DD/YYYY
+	dates := [2]string{}
+	for i, d := range []string{window.DateFrom, window.DateTo} {
+		if d == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, d)
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		dates[i] = url.QueryEscape(t.Format("01/02/2006"))
+	}
+	aheadBehind := url.QueryEscape(string(opts.AheadBehind))
+	clutchTime := url.QueryEscape(string(opts.ClutchTime))
+	requestURL := fmt.Sprintf(
+		"https://stats.nba.com/stats/videodetailsasset?AheadBehind=%s&ClutchTime=%s&ContextFilter=&DateFrom=%s&DateTo=%s&EndPeriod=%s&EndRange=&GameSegment=&LastNGames=0&LeagueID=&Location=&Month=0&OpponentTeamID=0&Outcome=&Period=%d&PointDiff=%s&Position=&RangeType=&RookieYear=&SeasonSegment=&StartPeriod=%s&StartRange=&TeamID=%d&VsConference=&VsDivision=&ContextMeasure=%s&GameID=%s&PlayerID=%s&Season=%s&SeasonType=%s",
+		aheadBehind, clutchTime, dates[0], dates[1], blankIfZero(opts.EndPeriod), opts.Period, blankIfZero(opts.PointDiff), blankIfZero(opts.StartPeriod),
+		teamID, contextMeasure, gameID, playerID, season, seasonType,
+	)
+	body, err := curlWithContext(ctx, requestURL)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
@@ -809,11 +1146,13 @@ func VideoDetailsAsset(season, gameID, playerID string, contextMeasure VideoDeta
 			Year:        Playlist[i].Year,
 			Month:       Playlist[i].Month,
 			Day:         Playlist[i].Day,
+			Period:      Playlist[i].Period,
 			Description: Playlist[i].Description,
 			Uuid:        VideoUrls[i].Uuid,
 			SmallUrl:    VideoUrls[i].SmallUrl,
 			MedUrl:      VideoUrls[i].MedUrl,
 			LargeUrl:    VideoUrls[i].LargeUrl,
+			IsDunk:      IsDunkDescription(Playlist[i].Description),
 		}
 		if entry.LargeUrl == nil && entry.MedUrl == nil && entry.SmallUrl == nil {
 			continue
@@ -1470,6 +1809,86 @@ func BoxScoreTraditionalV3(gameID string) (*BoxScoreTraditionalV3Data, error) {
 	return &unmarshalled.BoxScoreTraditional, nil
 }
 
+type PlayByPlayV3Resp struct {
+	Meta struct {
+		Version *float64 `json:"version"`
+		Request *string  `json:"request"`
+		Time    *string  `json:"time"`
+	} `json:"meta"`
+	Game PlayByPlayV3Game `json:"game"`
+}
+
+type PlayByPlayV3Game struct {
+	GameId         *string              `json:"gameId"`
+	VideoAvailable *float64             `json:"videoAvailable"`
+	Actions        []PlayByPlayV3Action `json:"actions"`
+}
+
+// A single play-by-play event. ActionNumber is the same event id the video
+// endpoints use, so clips can be matched to their events with it
+type PlayByPlayV3Action struct {
+	ActionNumber   *float64 `json:"actionNumber"`
+	Clock          *string  `json:"clock"`
+	Period         *float64 `json:"period"`
+	TeamId         *float64 `json:"teamId"`
+	TeamTricode    *string  `json:"teamTricode"`
+	PersonId       *float64 `json:"personId"`
+	PlayerName     *string  `json:"playerName"`
+	PlayerNameI    *string  `json:"playerNameI"`
+	XLegacy        *float64 `json:"xLegacy"`
+	YLegacy        *float64 `json:"yLegacy"`
+	ShotDistance   *float64 `json:"shotDistance"`
+	ShotResult     *string  `json:"shotResult"`
+	IsFieldGoal    *float64 `json:"isFieldGoal"`
+	ScoreHome      *string  `json:"scoreHome"`
+	ScoreAway      *string  `json:"scoreAway"`
+	PointsTotal    *float64 `json:"pointsTotal"`
+	Location       *string  `json:"location"`
+	Description    *string  `json:"description"`
+	ActionType     *string  `json:"actionType"`
+	SubType        *string  `json:"subType"`
+	VideoAvailable *float64 `json:"videoAvailable"`
+	ShotValue      *float64 `json:"shotValue"`
+	ActionId       *float64 `json:"actionId"`
+}
+
+// Matches the ISO 8601 durations the game clock comes in, i.e. "PT11M42.00S"
+var clockRegex = regexp.MustCompile(`^PT(\d+)M(\d+(?:\.\d+)?)S$`)
+
+// Seconds left in the period when the action happened
+func (a *PlayByPlayV3Action) SecondsRemaining() (float64, error) {
+	if a.Clock == nil {
+		return 0, utils.ErrorWithTrace(fmt.Errorf("nil clock " + utils.Sad))
+	}
+	match := clockRegex.FindStringSubmatch(*a.Clock)
+	if match == nil {
+		return 0, utils.ErrorWithTrace(fmt.Errorf("unable to parse clock '%s' "+utils.Sad, *a.Clock))
+	}
+	minutes, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, utils.ErrorWithTrace(err)
+	}
+	seconds, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return 0, utils.ErrorWithTrace(err)
+	}
+	return float64(minutes*60) + seconds, nil
+}
+
+func PlayByPlayV3(ctx context.Context, gameID string) (*PlayByPlayV3Game, error) {
+	url := fmt.Sprintf("https://stats.nba.com/stats/playbyplayv3?GameID=%s&StartPeriod=0&EndPeriod=0", gameID)
+	body, err := curlWithContext(ctx, url)
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+
+	unmarshalled := PlayByPlayV3Resp{}
+	if err := json.Unmarshal(body, &unmarshalled); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &unmarshalled.Game, nil
+}
+
 type TeamDetailsResp struct {
 	ResultSet []struct {
 		Name    string   `json:"name"`
@@ -1703,12 +2122,21 @@ var sem = make(chan int, 25)
 var rateLimiter = rate.NewLimiter(rate.Limit(25), 3)
 
 func curl(url string) ([]byte, error) {
-	sem <- 1
+	return curlWithContext(context.Background(), url)
+}
+
+// Gives up waiting on the semaphore, the rate limiter and the request itself once ctx is done
+func curlWithContext(ctx context.Context, url string) ([]byte, error) {
+	select {
+	case sem <- 1:
+	case <-ctx.Done():
+		return nil, utils.ErrorWithTrace(ctx.Err())
+	}
 	defer func() { <-sem }()
-	if err := rateLimiter.Wait(context.Background()); err != nil {
+	if err := rateLimiter.Wait(ctx); err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
@@ -1723,6 +2151,9 @@ func curl(url string) ([]byte, error) {
 		return nil, utils.ErrorWithTrace(err)
 	}
 	defer resp.Body.Close()
+	if err := utils.CheckStatus(resp); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -1731,6 +2162,14 @@ func curl(url string) ([]byte, error) {
 	return body, nil
 }
 
+// Leaves optional numeric query params empty rather than sending 0
+func blankIfZero(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
+
 func maybe[T any](x any) *T {
 	if x, ok := x.(T); ok {
 		return &x
@@ -1739,7 +2178,8 @@ func maybe[T any](x any) *T {
 	return nil
 }
 
-func gameIDToSeasonTypeString(id string) (string, error) {
+// The season type the NBA api expects for the game, i.e. Regular+Season
+func GameIDToSeasonTypeString(id string) (string, error) {
 	if strings.HasPrefix(id, "001") {
 		return "Pre+Season", nil
 	} else if strings.HasPrefix(id, "002") {
@@ -1754,3 +2194,18 @@ func gameIDToSeasonTypeString(id string) (string, error) {
 		return "", utils.ErrorWithTrace(fmt.Errorf("could not parse id " + utils.Sad))
 	}
 }
+
+// The different season types the games were played in, in the order they first come up
+func GameIDsToSeasonTypeStrings(ids []string) ([]string, error) {
+	seasonTypes := []string{}
+	for _, id := range ids {
+		seasonType, err := GameIDToSeasonTypeString(id)
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		if !slices.Contains(seasonTypes, seasonType) {
+			seasonTypes = append(seasonTypes, seasonType)
+		}
+	}
+	return seasonTypes, nil
+}
@@ -0,0 +1,159 @@
+package nba
+
+import "testing"
+
+func TestIsDunkDescription(t *testing.T) {
+	tests := []struct {
+		description *string
+		want        bool
+	}{
+		{nil, false},
+		{ptr("Zion 1' Driving Dunk (12 PTS) (Murphy III 3 AST)"), true},
+		{ptr("Gobert 2' Alley Oop Dunk (8 PTS)"), true},
+		{ptr("Antetokounmpo  Putback DUNK (24 PTS)"), true},
+		{ptr("MISS Zion 1' Dunk"), false},
+		{ptr("  MISS Gobert 2' Alley Oop Dunk"), false},
+		{ptr("MISS Zion 1' Dunk    Gobert BLOCK (2 BLK)"), false},
+		{ptr("Dunkin 26' 3PT Jump Shot (3 PTS)"), false},
+		{ptr("Curry 26' 3PT Jump Shot (3 PTS)"), false},
+		{ptr(""), false},
+	}
+	for _, tt := range tests {
+		if got := IsDunkDescription(tt.description); got != tt.want {
+			t.Errorf("IsDunkDescription(%q) = %v, want %v", deref(tt.description), got, tt.want)
+		}
+	}
+}
+
+func TestSecondsRemaining(t *testing.T) {
+	tests := []struct {
+		clock   *string
+		want    float64
+		wantErr bool
+	}{
+		{ptr("PT12M00.00S"), 720, false},
+		{ptr("PT11M42.00S"), 702, false},
+		{ptr("PT00M00.50S"), 0.5, false},
+		{ptr("PT05M07S"), 307, false},
+		{nil, 0, true},
+		{ptr(""), 0, true},
+		{ptr("11:42"), 0, true},
+		{ptr("PT11M42.00S "), 0, true},
+		{ptr("PT11MS"), 0, true},
+	}
+	for _, tt := range tests {
+		a := PlayByPlayV3Action{Clock: tt.clock}
+		got, err := a.SecondsRemaining()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("SecondsRemaining(%q) = (%v, %v), want (%v, error %v)", deref(tt.clock), got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVideoDetailsAssetOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    VideoDetailsAssetOptions
+		wantErr bool
+	}{
+		{"zero value", VideoDetailsAssetOptions{}, false},
+		{"clutch time", VideoDetailsAssetOptions{ClutchTime: "Last 2 Minutes"}, false},
+		{"unknown clutch time", VideoDetailsAssetOptions{ClutchTime: "Last 6 Minutes"}, true},
+		{"point diff", VideoDetailsAssetOptions{AheadBehind: AheadBehinds.BehindOrTied, PointDiff: 3}, false},
+		{"unknown ahead or behind", VideoDetailsAssetOptions{AheadBehind: "Way Ahead", PointDiff: 3}, true},
+		{"negative point diff", VideoDetailsAssetOptions{AheadBehind: AheadBehinds.AheadOrTied, PointDiff: -1}, true},
+		{"point diff alone", VideoDetailsAssetOptions{PointDiff: 5}, true},
+		{"ahead or behind alone", VideoDetailsAssetOptions{AheadBehind: AheadBehinds.AheadOrBehind}, true},
+		{"period", VideoDetailsAssetOptions{Period: 4}, false},
+		{"last overtime", VideoDetailsAssetOptions{Period: MaxPeriod}, false},
+		{"period past the last overtime", VideoDetailsAssetOptions{Period: MaxPeriod + 1}, true},
+		{"negative period", VideoDetailsAssetOptions{Period: -1}, true},
+		{"period range", VideoDetailsAssetOptions{StartPeriod: 2, EndPeriod: 3}, false},
+		{"open ended period range", VideoDetailsAssetOptions{StartPeriod: 5}, false},
+		{"single period range", VideoDetailsAssetOptions{StartPeriod: 3, EndPeriod: 3}, false},
+		{"backwards period range", VideoDetailsAssetOptions{StartPeriod: 3, EndPeriod: 2}, true},
+		{"end period past the last overtime", VideoDetailsAssetOptions{EndPeriod: MaxPeriod + 1}, true},
+		{"period and a range", VideoDetailsAssetOptions{Period: 4, StartPeriod: 1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVideoDetailsAssetOptionsKey(t *testing.T) {
+	tests := []struct {
+		opts VideoDetailsAssetOptions
+		want string
+	}{
+		{VideoDetailsAssetOptions{}, ""},
+		{VideoDetailsAssetOptions{Period: 4}, "period=4"},
+		{VideoDetailsAssetOptions{StartPeriod: 2}, "periods=2-0"},
+		{VideoDetailsAssetOptions{EndPeriod: 2}, "periods=0-2"},
+		{VideoDetailsAssetOptions{ClutchTime: "Last 2 Minutes", AheadBehind: AheadBehinds.AheadOrTied, PointDiff: 3}, "clutch=Last 2 Minutes,diff=Ahead or Tied 3"},
+		{VideoDetailsAssetOptions{ClutchTime: "Last 5 Minutes", AheadBehind: AheadBehinds.AheadOrBehind, PointDiff: 5, StartPeriod: 4}, "clutch=Last 5 Minutes,diff=Ahead or Behind 5,periods=4-0"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Key(); got != tt.want {
+			t.Errorf("%+v.Key() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestVideoDetailsAssetOptionsNarrows(t *testing.T) {
+	tests := []struct {
+		name string
+		opts VideoDetailsAssetOptions
+		want bool
+	}{
+		{"zero value", VideoDetailsAssetOptions{}, false},
+		{"clutch preset", ClutchPreset, true},
+		{"clutch time", VideoDetailsAssetOptions{ClutchTime: "Last 1 Minute"}, true},
+		{"point diff", VideoDetailsAssetOptions{AheadBehind: AheadBehinds.AheadOrBehind, PointDiff: 10}, true},
+		{"first period", VideoDetailsAssetOptions{Period: 1}, true},
+		{"from the first period on", VideoDetailsAssetOptions{StartPeriod: 1}, false},
+		{"every period", VideoDetailsAssetOptions{StartPeriod: 1, EndPeriod: MaxPeriod}, false},
+		{"up to the last overtime", VideoDetailsAssetOptions{EndPeriod: MaxPeriod}, false},
+		{"second half", VideoDetailsAssetOptions{StartPeriod: 3}, true},
+		{"regulation", VideoDetailsAssetOptions{StartPeriod: 1, EndPeriod: 4}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Narrows(); got != tt.want {
+			t.Errorf("%s: Narrows() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVideoDetailsAssetOptionsIsClutch(t *testing.T) {
+	tighter := ClutchPreset
+	tighter.PointDiff = 3
+	tests := []struct {
+		name string
+		opts VideoDetailsAssetOptions
+		want bool
+	}{
+		{"clutch preset", ClutchPreset, true},
+		{"zero value", VideoDetailsAssetOptions{}, false},
+		{"closer game", tighter, false},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.IsClutch(); got != tt.want {
+			t.Errorf("%s: IsClutch() = %v, want %v", tt.name, got, tt.want)
+		}
+		if err := tt.opts.Validate(); err != nil {
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
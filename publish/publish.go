@@ -0,0 +1,118 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"dunkod/config"
+	"dunkod/utils"
+	"dunkod/youtube"
+)
+
+const LocalPublisherName = "local"
+
+// Where the local publisher's videos are served from
+const LocalURLPrefix = "/videos/"
+
+// Somewhere a finished video can be put for people to watch
+type Publisher interface {
+	Name() string
+	// Returns the url the video can be watched at. onProgress may be nil
+	Publish(ctx context.Context, filepath, title, description string, tags []string, onProgress func(sent, total int64)) (string, error)
+}
+
+// Picks the publisher named by the -publisher flag
+func FromConfig() (Publisher, error) {
+	switch *config.PublisherName {
+	case youtube.PublisherName:
+		p, err := youtube.NewPublisher()
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		return p, nil
+	case LocalPublisherName:
+		p, err := NewLocalPublisher(config.VideosDir)
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		return p, nil
+	default:
+		return nil, utils.ErrorWithTrace(fmt.Errorf("unknown publisher '%s'", *config.PublisherName))
+	}
+}
+
+// Moves videos into a directory the web server serves at LocalURLPrefix
+type LocalPublisher struct {
+	Dir string
+}
+
+func NewLocalPublisher(dir string) (*LocalPublisher, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &LocalPublisher{
+		Dir: dir,
+	}, nil
+}
+
+func (p *LocalPublisher) Name() string {
+	return LocalPublisherName
+}
+
+func (p *LocalPublisher) Publish(ctx context.Context, path, title, description string, tags []string, onProgress func(sent, total int64)) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	name := filepath.Base(path)
+	if err := moveFile(path, filepath.Join(p.Dir, name)); err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	if onProgress != nil {
+		onProgress(info.Size(), info.Size())
+	}
+	return LocalURLPrefix + name, nil
+}
+
+// Renames when it can, copies when src and dst are on different devices
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	defer in.Close()
+
+	tmp := dst + ".partial"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return utils.ErrorWithTrace(err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return utils.ErrorWithTrace(err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return utils.ErrorWithTrace(err)
+	}
+	if err := os.Remove(src); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"dunkod/cache"
 	"dunkod/config"
 	"dunkod/db"
 	"dunkod/nba"
@@ -51,6 +54,22 @@ func Scrape() error {
 	if err := rescrapeBoxScoreErrors(); err != nil {
 		return utils.ErrorWithTrace(err)
 	}
+	log.Printf("Scraping Play By Play for the last %d Days of Games\n", n)
+	if err := scrapeLastNGamePlayByPlay(n); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	log.Println("Re-Scraping prior Play By Play Scraping Errors")
+	if err := rescrapePlayByPlayErrors(); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	log.Printf("Scraping Assets for the last %d Days of Games\n", n)
+	if err := scrapeLastNGameAssets(n); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	log.Println("Re-Scraping prior Asset Scraping Errors")
+	if err := rescrapeAssetErrors(); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
 	log.Println("Finished Scraping")
 	return nil
 }
@@ -72,6 +91,10 @@ func BigScrape() error {
 	if err := ScrapeAllBoxScores(); err != nil {
 		return utils.ErrorWithTrace(err)
 	}
+	log.Printf("Scraping all Play By Play")
+	if err := ScrapeAllPlayByPlay(); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
 	log.Println("Finished Scraping")
 	return nil
 }
@@ -260,6 +283,438 @@ func rescrapeBoxScoreErrors() error {
 	return nil
 }
 
+func ScrapeAllPlayByPlay() error {
+	for _, s := range config.ValidSeasons {
+		log.Println(s)
+		games, err := db.SelectGamesWithoutPlayByPlay(s)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := ScrapeGamesPlayByPlay(games); err != nil {
+			log.Println(err)
+		}
+		time.Sleep(10 * time.Second)
+	}
+	return nil
+}
+
+// The NBA tidies up the play-by-play for a little while after the game, so the
+// last few days of finished games are scraped again each time
+func scrapeLastNGamePlayByPlay(n int) error {
+	games, err := db.SelectGamesPastNDays(n)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := ScrapeGamesPlayByPlay(finishedGames(games)); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// Stores every event of the provided games, replacing any the games already had.
+// Failed games are recorded for rescrapePlayByPlayErrors to have another go at
+func ScrapeGamesPlayByPlay(games []db.DatabaseGame) error {
+	scrapingErrs := scrapeGamesPlayByPlay(games)
+	if err := db.InsertPlayByPlayScrapingErrors(scrapingErrs); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func rescrapePlayByPlayErrors() error {
+	games, err := db.SelectGamesWithPendingPlayByPlayScrapingErrors()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	// games that fail again stay pending rather than piling up more errors
+	failed := map[string]bool{}
+	for _, e := range scrapeGamesPlayByPlay(games) {
+		failed[e.GameID] = true
+	}
+	resolved := []string{}
+	for _, g := range games {
+		if !failed[g.ID] {
+			resolved = append(resolved, g.ID)
+		}
+	}
+	if err := db.UpdateResolvedPlayByPlayScrapingErrors(resolved); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func scrapeGamesPlayByPlay(games []db.DatabaseGame) []db.PlayByPlayScrapingError {
+	log.Printf("querying play by play for %d games...", len(games))
+	if len(games) == 0 {
+		return nil
+	}
+	timeout := 2 * time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mu := sync.Mutex{}
+	scrapingErrs := []db.PlayByPlayScrapingError{}
+	scraped := 0
+	limiter := rate.NewLimiter(rate.Limit(5), 3) // let's try not to blow up the nba API if we can help it
+	wg := sync.WaitGroup{}
+
+	for _, g := range games {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := limiter.Wait(ctx)
+			if err == nil {
+				err = scrapePlayByPlay(ctx, g.ID)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				scrapeErr := utils.ErrorWithTrace(fmt.Errorf("unable to scrape play by play for %s: %w", g.ID, err))
+				log.Println(scrapeErr)
+				scrapingErrs = append(scrapingErrs, *db.NewPlayByPlayScrapingError(g.ID, scrapeErr))
+				return
+			}
+			scraped++
+			if scraped%100 == 0 {
+				log.Printf("Processed %d Games, %d Errors", scraped, len(scrapingErrs))
+			}
+		}()
+	}
+
+	wg.Wait()
+	log.Printf("Processed %d Games, %d Errors", scraped, len(scrapingErrs))
+	return scrapingErrs
+}
+
+func scrapePlayByPlay(ctx context.Context, gameID string) error {
+	game, err := nba.PlayByPlayV3(ctx, gameID)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	events, err := toPlayByPlayEvents(gameID, game.Actions)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if err := db.InsertPlayByPlay(gameID, events); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func toPlayByPlayEvents(gameID string, actions []nba.PlayByPlayV3Action) ([]db.PlayByPlayEvent, error) {
+	events := make([]db.PlayByPlayEvent, 0, len(actions))
+	names := newRosterNames(actions)
+	// the score is left blank on events that don't change it
+	scoreHome, scoreAway := 0, 0
+	for _, a := range actions {
+		if a.ActionNumber == nil || a.Period == nil || a.Clock == nil {
+			return nil, utils.ErrorWithTrace(fmt.Errorf("event missing its number, period or clock in %s "+utils.Sad, gameID))
+		}
+		secondsRemaining, err := a.SecondsRemaining()
+		if err != nil {
+			return nil, utils.ErrorWithTrace(err)
+		}
+		if score, err := strconv.Atoi(derefString(a.ScoreHome)); err == nil {
+			scoreHome = score
+		}
+		if score, err := strconv.Atoi(derefString(a.ScoreAway)); err == nil {
+			scoreAway = score
+		}
+		involved := names.involved(a)
+		event := db.NewPlayByPlayEvent(
+			gameID,
+			int(*a.ActionNumber),
+			optionalInt(a.ActionId),
+			int(*a.Period),
+			*a.Clock,
+			secondsRemaining,
+			optionalID(a.TeamId),
+			optionalID(a.PersonId),
+			optionalString(a.PlayerName),
+			involved.assist,
+			involved.steal,
+			involved.block,
+			derefString(a.ActionType),
+			optionalString(a.SubType),
+			optionalString(a.Description),
+			optionalInt(a.ShotDistance),
+			optionalString(a.ShotResult),
+			optionalInt(a.ShotValue),
+			a.IsFieldGoal != nil && *a.IsFieldGoal == 1,
+			optionalInt(a.XLegacy),
+			optionalInt(a.YLegacy),
+			optionalString(a.Location),
+			scoreHome,
+			scoreAway,
+			a.VideoAvailable != nil && *a.VideoAvailable == 1,
+		)
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+// Credits the play-by-play tacks onto a description, i.e.
+//
+//	"Gobert 1' Alley Oop Dunk (2 PTS) (Conley 4 AST)"
+//	"Davis Bad Pass Turnover (P1.T3) Curry STEAL (1 STL)"
+//	"MISS Zion 1' Dunk    Gobert BLOCK (2 BLK)"
+var assistRegex = regexp.MustCompile(`\(([^()]+?) \d+ AST\)`)
+var stealRegex = regexp.MustCompile(`^(.*?)\s*STEAL \(\d+ STL\)`)
+var blockRegex = regexp.MustCompile(`^(.*?)\s*BLOCK \(\d+ BLK\)`)
+
+// Whoever else an event credits besides the player it's for
+type involvedPlayers struct {
+	assist *int
+	steal  *int
+	block  *int
+}
+
+// The names the play-by-play calls each team's players in a game, mapped to their
+// ids. A name two players on a team share maps to 0, there's no telling them apart
+type rosterNames map[int]map[string]int
+
+func newRosterNames(actions []nba.PlayByPlayV3Action) rosterNames {
+	names := rosterNames{}
+	for _, a := range actions {
+		teamID, personID := optionalID(a.TeamId), optionalID(a.PersonId)
+		name := derefString(a.PlayerName)
+		if teamID == nil || personID == nil || name == "" {
+			continue
+		}
+		if names[*teamID] == nil {
+			names[*teamID] = map[string]int{}
+		}
+		if existing, ok := names[*teamID][name]; ok && existing != *personID {
+			names[*teamID][name] = 0
+			continue
+		}
+		names[*teamID][name] = *personID
+	}
+	return names
+}
+
+// Assists come from the event's own team. Steals and blocks come from the other
+// team, and the name is whatever ends the text before them
+func (r rosterNames) involved(a nba.PlayByPlayV3Action) involvedPlayers {
+	description := derefString(a.Description)
+	teamID := 0
+	if id := optionalID(a.TeamId); id != nil {
+		teamID = *id
+	}
+	involved := involvedPlayers{}
+	if match := assistRegex.FindStringSubmatch(description); match != nil {
+		involved.assist = r.find([]int{teamID}, match[1], false)
+	}
+	if match := stealRegex.FindStringSubmatch(description); match != nil {
+		involved.steal = r.find(r.otherTeamsFirst(teamID), match[1], true)
+	}
+	if match := blockRegex.FindStringSubmatch(description); match != nil {
+		involved.block = r.find(r.otherTeamsFirst(teamID), match[1], true)
+	}
+	return involved
+}
+
+// The other team first, then the team itself for events that are the steal or
+// block on their own
+func (r rosterNames) otherTeamsFirst(teamID int) []int {
+	teams := make([]int, 0, len(r))
+	for id := range r {
+		if id != teamID {
+			teams = append(teams, id)
+		}
+	}
+	return append(teams, teamID)
+}
+
+// The first team with a player going by the text, or by the end of it when
+// suffix is set, the longest name winning. Nil when nobody or more than one player fits
+func (r rosterNames) find(teamIDs []int, text string, suffix bool) *int {
+	text = strings.TrimSpace(text)
+	for _, teamID := range teamIDs {
+		best, bestID := "", 0
+		for name, id := range r[teamID] {
+			fits := text == name || (suffix && strings.HasSuffix(text, " "+name))
+			if fits && len(name) > len(best) {
+				best, bestID = name, id
+			}
+		}
+		if best == "" {
+			continue
+		}
+		if bestID == 0 {
+			return nil
+		}
+		return &bestID
+	}
+	return nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optionalString(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
+func optionalInt(f *float64) *int {
+	if f == nil {
+		return nil
+	}
+	i := int(*f)
+	return &i
+}
+
+// The NBA sends 0 for events without a team or player, like timeouts and period starts
+func optionalID(f *float64) *int {
+	if f == nil || *f == 0 {
+		return nil
+	}
+	return optionalInt(f)
+}
+
+func scrapeLastNGameAssets(n int) error {
+	games, err := db.SelectGamesPastNDays(n)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	// The NBA keeps adding clips for a while after the final buzzer. Cached lookups
+	// only count once the game day is over, so wait until then to scrape a game.
+	if err := ScrapeGamesAssets(finishedGames(games)); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+// Games whose game day is over
+func finishedGames(games []db.DatabaseGame) []db.DatabaseGame {
+	today := time.Now().Format("2006-01-02")
+	finished := make([]db.DatabaseGame, 0, len(games))
+	for _, g := range games {
+		if g.GameDate < today {
+			finished = append(finished, g)
+		}
+	}
+	return finished
+}
+
+// Caches the clips of every player who saw the floor in the provided games for every selectable context measure
+func ScrapeGamesAssets(games []db.DatabaseGame) error {
+	gameIDs := make([]string, 0, len(games))
+	for _, g := range games {
+		gameIDs = append(gameIDs, g.ID)
+	}
+	stats, err := db.SelectBoxScorePlayerStatsWithMinutes(gameIDs)
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	scrapingErrs := scrapeAssets(stats)
+	if err := db.InsertAssetScrapingErrors(scrapingErrs); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
+func scrapeAssets(stats []db.BoxScorePlayerStat) []db.AssetScrapingError {
+	log.Printf("querying assets for %d box score entries...", len(stats))
+	if len(stats) == 0 {
+		return nil
+	}
+	timeout := 2 * time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mu := sync.Mutex{}
+	scrapingErrs := []db.AssetScrapingError{}
+	queried := 0
+	limiter := rate.NewLimiter(rate.Limit(5), 3) // let's try not to blow up the nba API if we can help it
+	wg := sync.WaitGroup{}
+
+	for _, s := range stats {
+		for _, m := range nba.SelectableContextMeasures {
+			_, found, err := db.SelectCachedAssets(s.GameID, s.PlayerID, *db.NewContextMeasure(string(m)))
+			if err != nil {
+				log.Println(utils.ErrorWithTrace(err))
+			} else if found {
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := limiter.Wait(ctx); err != nil {
+					timeoutErr := fmt.Errorf("timed out after %s while waiting to query %s %d %s "+utils.Sad, timeout, s.GameID, s.PlayerID, m)
+					scrapeErr := db.NewAssetScrapingError(s.GameID, s.PlayerID, s.Season, string(m), utils.ErrorWithTrace(errors.Join(timeoutErr, err)))
+					mu.Lock()
+					defer mu.Unlock()
+					scrapingErrs = append(scrapingErrs, *scrapeErr)
+					return
+				}
+
+				_, err := cache.Refresh(context.Background(), s.Season, s.GameID, strconv.Itoa(s.PlayerID), m)
+				mu.Lock()
+				defer mu.Unlock()
+				queried++
+				if err != nil {
+					scrapeErr := db.NewAssetScrapingError(s.GameID, s.PlayerID, s.Season, string(m), err)
+					scrapingErrs = append(scrapingErrs, *scrapeErr)
+				}
+				if queried%100 == 0 {
+					log.Printf("Processed %d Asset Lookups, %d Errors", queried, len(scrapingErrs))
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+	log.Printf("Processed %d Asset Lookups, %d Errors", queried, len(scrapingErrs))
+	return scrapingErrs
+}
+
+func rescrapeAssetErrors() error {
+	pending, err := db.SelectPendingAssetScrapingErrors()
+	if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	// the same lookup can fail across several scrapes, only retry it once
+	type lookup struct {
+		gameID         string
+		playerID       int
+		season         string
+		contextMeasure string
+	}
+	idsByLookup := map[lookup][]int{}
+	for _, p := range pending {
+		l := lookup{p.GameID, p.PlayerID, p.Season, p.ContextMeasure}
+		idsByLookup[l] = append(idsByLookup[l], p.Id)
+	}
+
+	limiter := rate.NewLimiter(rate.Limit(5), 3)
+	resolved := []int{}
+	for l, ids := range idsByLookup {
+		if err := limiter.Wait(context.Background()); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		m := nba.VideoDetailsAssetContextMeasure(l.contextMeasure)
+		if _, err := cache.Refresh(context.Background(), l.season, l.gameID, strconv.Itoa(l.playerID), m); err != nil {
+			log.Println(err)
+			continue
+		}
+		resolved = append(resolved, ids...)
+	}
+	if err := db.UpdateResolvedAssetScrapingErrors(resolved); err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	return nil
+}
+
 func scrapeAllPlayers() error {
 	players, err := nba.CommonAllPlayerAllSeasons()
 	if err != nil {
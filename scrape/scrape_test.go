@@ -0,0 +1,79 @@
+package scrape
+
+import (
+	"strconv"
+	"testing"
+
+	"dunkod/nba"
+)
+
+var testRoster = rosterNames{
+	1: {"Gobert": 10, "Conley": 11, "Curry": 13, "Williams": 0},
+	2: {"Davis": 21, "Zion": 22, "Williams": 23, "J. Williams": 24},
+}
+
+func TestRosterNamesFind(t *testing.T) {
+	tests := []struct {
+		name    string
+		teamIDs []int
+		text    string
+		suffix  bool
+		want    *int
+	}{
+		{"exact name", []int{1}, "Conley", false, intPtr(11)},
+		{"surrounding spaces", []int{1}, " Conley ", false, intPtr(11)},
+		{"nobody by that name", []int{1, 2}, "Jokic", false, nil},
+		{"another team's player", []int{1}, "Davis", false, nil},
+		{"falls through to the next team", []int{2, 1}, "Gobert", false, intPtr(10)},
+		{"shared name", []int{1, 2}, "Williams", false, nil},
+		{"shared name on the other team", []int{2, 1}, "Williams", false, intPtr(23)},
+		{"end of the text", []int{1, 2}, "Davis Bad Pass Turnover (P1.T3) Curry", true, intPtr(13)},
+		{"end of the text without suffix", []int{1, 2}, "Davis Bad Pass Turnover (P1.T3) Curry", false, nil},
+		{"end of a longer word", []int{1}, "MISS Zion 1' Dunk    McCurry", true, nil},
+		{"longest name wins", []int{2}, "MISS Gobert 2' Layup    J. Williams", true, intPtr(24)},
+	}
+	for _, tt := range tests {
+		if got := testRoster.find(tt.teamIDs, tt.text, tt.suffix); !sameID(got, tt.want) {
+			t.Errorf("%s: find(%v, %q, %v) = %s, want %s", tt.name, tt.teamIDs, tt.text, tt.suffix, idString(got), idString(tt.want))
+		}
+	}
+}
+
+func TestRosterNamesInvolved(t *testing.T) {
+	tests := []struct {
+		description string
+		teamID      float64
+		want        involvedPlayers
+	}{
+		{"Gobert 1' Alley Oop Dunk (2 PTS) (Conley 4 AST)", 1, involvedPlayers{assist: intPtr(11)}},
+		{"Zion 1' Driving Dunk (2 PTS) (Davis 1 AST)", 1, involvedPlayers{}},
+		{"Davis Bad Pass Turnover (P1.T3) Curry STEAL (1 STL)", 2, involvedPlayers{steal: intPtr(13)}},
+		{"Curry STEAL (1 STL)", 1, involvedPlayers{steal: intPtr(13)}},
+		{"MISS Zion 1' Dunk    Gobert BLOCK (2 BLK)", 2, involvedPlayers{block: intPtr(10)}},
+		{"MISS Conley 26' 3PT Jump Shot    J. Williams BLOCK (1 BLK)", 1, involvedPlayers{block: intPtr(24)}},
+		{"Curry 26' 3PT Jump Shot (3 PTS)", 1, involvedPlayers{}},
+	}
+	for _, tt := range tests {
+		got := testRoster.involved(nba.PlayByPlayV3Action{Description: &tt.description, TeamId: &tt.teamID})
+		if !sameID(got.assist, tt.want.assist) || !sameID(got.steal, tt.want.steal) || !sameID(got.block, tt.want.block) {
+			t.Errorf("involved(%q) = assist %s steal %s block %s, want assist %s steal %s block %s", tt.description,
+				idString(got.assist), idString(got.steal), idString(got.block),
+				idString(tt.want.assist), idString(tt.want.steal), idString(tt.want.block))
+		}
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func sameID(a, b *int) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func idString(id *int) string {
+	if id == nil {
+		return "<nil>"
+	}
+	return strconv.Itoa(*id)
+}
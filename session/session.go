@@ -0,0 +1,79 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"dunkod/config"
+	"dunkod/utils"
+)
+
+const CookieName = "dunkod_session"
+
+const keySize = 32
+
+var key []byte
+
+// Loads the signing key from config.SessionKeyFile, making one if it doesn't
+// exist yet. Changing the key logs everyone out of their reels
+func Init() error {
+	b, err := os.ReadFile(config.SessionKeyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		b = make([]byte, keySize)
+		if _, err := rand.Read(b); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+		if err := os.WriteFile(config.SessionKeyFile, b, 0600); err != nil {
+			return utils.ErrorWithTrace(err)
+		}
+	} else if err != nil {
+		return utils.ErrorWithTrace(err)
+	}
+	if len(b) < keySize {
+		return utils.ErrorWithTrace(fmt.Errorf("session key in %s is %d bytes, want at least %d", config.SessionKeyFile, len(b), keySize))
+	}
+	key = b
+	return nil
+}
+
+// Random id for a new anonymous session
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Cookie value for the session id, the id and its signature separated by a dot
+func Sign(id string) string {
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac(id))
+}
+
+// Returns the session id from a cookie value made by Sign, or false if it was
+// tampered with or signed with another key
+func Verify(value string) (string, bool) {
+	id, sig, found := strings.Cut(value, ".")
+	if !found || id == "" {
+		return "", false
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(decoded, mac(id)) {
+		return "", false
+	}
+	return id, true
+}
+
+func mac(id string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(id))
+	return h.Sum(nil)
+}
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	key = bytes.Repeat([]byte{1}, keySize)
+	signed := Sign("0123456789abcdef")
+	id, sig, _ := strings.Cut(signed, ".")
+
+	key = bytes.Repeat([]byte{2}, keySize)
+	otherKey := Sign("0123456789abcdef")
+	key = bytes.Repeat([]byte{1}, keySize)
+
+	tests := []struct {
+		name   string
+		value  string
+		wantID string
+		wantOK bool
+	}{
+		{"round trip", signed, "0123456789abcdef", true},
+		{"tampered id", "0123456789abcdee." + sig, "", false},
+		{"tampered signature", id + "." + strings.ToUpper(sig), "", false},
+		{"signature that isn't base64", id + ".!!!", "", false},
+		{"no signature", id, "", false},
+		{"empty id", Sign(""), "", false},
+		{"empty", "", "", false},
+		{"signed with another key", otherKey, "", false},
+	}
+	for _, tt := range tests {
+		gotID, gotOK := Verify(tt.value)
+		if gotID != tt.wantID || gotOK != tt.wantOK {
+			t.Errorf("%s: Verify(%q) = (%q, %v), want (%q, %v)", tt.name, tt.value, gotID, gotOK, tt.wantID, tt.wantOK)
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand/v2"
+	"net"
 	"net/http"
 	"os"
 	"runtime"
 	"slices"
+	"syscall"
 	"time"
 	"unicode"
 
@@ -22,7 +26,44 @@ const Sad = "(◞‸ ◟ ；)"
 
 func ErrorWithTrace(e error) error {
 	_, file, line, _ := runtime.Caller(1)
-	return fmt.Errorf("%s:%d\n\t%v", file, line, e)
+	return fmt.Errorf("%s:%d\n\t%w", file, line, e)
+}
+
+// Returned when a request goes through but the server answers with a 4xx or 5xx
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s responded with %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Turns 4xx and 5xx responses into an HTTPStatusError
+func CheckStatus(resp *http.Response) error {
+	if resp.StatusCode >= 400 {
+		return &HTTPStatusError{URL: resp.Request.URL.String(), StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// True for failures that are likely to go away on their own: timeouts, dropped
+// connections, rate limiting and 5xx responses. Anything else is considered permanent
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// every *url.Error is a net.Error, bad certificates and unknown hosts included,
+	// so only timeouts count
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 func IsInvalidSeason(season string) bool {
@@ -31,13 +72,17 @@ func IsInvalidSeason(season string) bool {
 
 var sem = make(chan int, 50)
 
-func CurlToFile(url, filepath string) error {
-	sem <- 1
+func CurlToFile(ctx context.Context, url, filepath string) error {
+	select {
+	case sem <- 1:
+	case <-ctx.Done():
+		return ErrorWithTrace(ctx.Err())
+	}
 	defer func() { <-sem }()
 	client := &http.Client{
 		Timeout: time.Minute,
 	}
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return ErrorWithTrace(err)
 	}
@@ -47,6 +92,9 @@ func CurlToFile(url, filepath string) error {
 		return ErrorWithTrace(err)
 	}
 	defer resp.Body.Close()
+	if err := CheckStatus(resp); err != nil {
+		return ErrorWithTrace(err)
+	}
 
 	out, err := os.Create(filepath)
 	if err != nil {
@@ -72,6 +120,9 @@ func Curl(req *http.Request) ([]byte, error) {
 		return nil, ErrorWithTrace(err)
 	}
 	defer resp.Body.Close()
+	if err := CheckStatus(resp); err != nil {
+		return nil, ErrorWithTrace(err)
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
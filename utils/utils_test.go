@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"canceled wrapped", ErrorWithTrace(context.Canceled), false},
+		{"deadline exceeded", ErrorWithTrace(context.DeadlineExceeded), true},
+		{"429", &HTTPStatusError{URL: "https://stats.nba.com", StatusCode: 429}, true},
+		{"503", ErrorWithTrace(&HTTPStatusError{URL: "https://stats.nba.com", StatusCode: 503}), true},
+		{"404", &HTTPStatusError{URL: "https://stats.nba.com", StatusCode: 404}, false},
+		{"unexpected eof", ErrorWithTrace(io.ErrUnexpectedEOF), true},
+		{"connection reset", &url.Error{Op: "Get", URL: "https://stats.nba.com", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, true},
+		{"connection refused", ErrorWithTrace(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"timeout", &url.Error{Op: "Get", URL: "https://stats.nba.com", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, true},
+		{"unknown host", &url.Error{Op: "Get", URL: "https://stats.nba.com", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+		{"bad certificate", &url.Error{Op: "Get", URL: "https://stats.nba.com", Err: x509.UnknownAuthorityError{}}, false},
+		{"anything else", errors.New("no rows"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransient(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
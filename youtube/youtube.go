@@ -3,11 +3,15 @@
 package youtube
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -17,7 +21,6 @@ import (
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
@@ -25,7 +28,8 @@ import (
 var tokenMu = sync.Mutex{}
 var secretMu = sync.Mutex{}
 
-var service *youtube.Service
+// Authorised client for the upload api, refreshed by ServiceJanitor
+var httpClient *http.Client
 var serviceMut = sync.RWMutex{}
 
 func GetClient(ctx context.Context, oauthConfig *oauth2.Config) (*http.Client, error) {
@@ -55,7 +59,7 @@ func GetClient(ctx context.Context, oauthConfig *oauth2.Config) (*http.Client, e
 	return oauthConfig.Client(ctx, tok), nil
 }
 
-func GetService() (*youtube.Service, error) {
+func GetHTTPClient() (*http.Client, error) {
 	oauthConfig, err := OAuthConfig()
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
@@ -64,9 +68,15 @@ func GetService() (*youtube.Service, error) {
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
-	ctx := context.Background()
-	client := oauthConfig.Client(ctx, token)
-	service, err := youtube.NewService(ctx, option.WithHTTPClient(client))
+	return oauthConfig.Client(context.Background(), token), nil
+}
+
+func GetService() (*youtube.Service, error) {
+	client, err := GetHTTPClient()
+	if err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	service, err := youtube.NewService(context.Background(), option.WithHTTPClient(client))
 	if err != nil {
 		return nil, utils.ErrorWithTrace(err)
 	}
@@ -166,12 +176,29 @@ func OAuthConfig() (*oauth2.Config, error) {
 	return oauthConfig, nil
 }
 
-func UploadFile(filepath, title, description string, tags []string) (string, error) {
+const uploadURL = "https://www.googleapis.com/upload/youtube/v3/videos?uploadType=resumable&part=snippet,status"
+const chunkSize = 32 * 1024 * 1024
+
+// onProgress is called after every uploaded chunk with the bytes sent so far and the file size.
+// The upload session is saved next to the file, so if the upload is interrupted the next
+// upload of the same file carries on from the last chunk YouTube received. The
+// finished video's id is saved there too, so a job that stopped before recording
+// the video gets the same one back instead of uploading it again
+func UploadFile(ctx context.Context, filepath, title, description string, tags []string, onProgress func(sent, total int64)) (string, error) {
+	videoFile := filepath + ".video"
+	if id, err := os.ReadFile(videoFile); err == nil && len(id) > 0 {
+		return embedURL(string(id)), nil
+	}
 	file, err := os.Open(filepath)
 	if err != nil {
 		return "", utils.ErrorWithTrace(err)
 	}
 	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	size := info.Size()
 	snippet := &youtube.VideoSnippet{
 		Title:       title,
 		Description: description,
@@ -190,20 +217,172 @@ func UploadFile(filepath, title, description string, tags []string) (string, err
 		Status:  status,
 	}
 	serviceMut.RLock()
-	defer serviceMut.RUnlock()
-	call := service.Videos.Insert([]string{"snippet", "status"}, upload)
-	resp, err := call.Media(file, googleapi.ChunkSize(32*1024*1024)).Do()
+	client := httpClient
+	serviceMut.RUnlock()
+
+	sessionFile := filepath + ".upload"
+	var video *youtube.Video
+	var offset int64
+	session, err := os.ReadFile(sessionFile)
+	if err == nil {
+		video, offset, err = querySession(ctx, client, string(session), size)
+		var statusErr *utils.HTTPStatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone) {
+			// sessions expire after a week, start over
+			session = nil
+		} else if err != nil {
+			return "", utils.ErrorWithTrace(err)
+		}
+	}
+	if session == nil {
+		uri, err := startSession(ctx, client, upload, size)
+		if err != nil {
+			return "", utils.ErrorWithTrace(err)
+		}
+		if err := os.WriteFile(sessionFile, []byte(uri), 0600); err != nil {
+			return "", utils.ErrorWithTrace(err)
+		}
+		session = []byte(uri)
+	}
+
+	for video == nil {
+		if onProgress != nil {
+			onProgress(offset, size)
+		}
+		video, offset, err = sendChunk(ctx, client, string(session), file, offset, size)
+		if err != nil {
+			return "", utils.ErrorWithTrace(err)
+		}
+	}
+	if onProgress != nil {
+		onProgress(size, size)
+	}
+	if err := os.WriteFile(videoFile, []byte(video.Id), 0600); err != nil {
+		// the video is up either way, failing now would only upload it again
+		log.Println(utils.ErrorWithTrace(err))
+	}
+	_ = os.Remove(sessionFile)
+	return embedURL(video.Id), nil
+}
+
+func embedURL(videoID string) string {
+	return fmt.Sprintf("https://www.youtube.com/embed/%s", videoID)
+}
+
+// Creates a resumable upload session for the video and returns its uri
+func startSession(ctx context.Context, client *http.Client, upload *youtube.Video, size int64) (string, error) {
+	body, err := json.Marshal(upload)
+	if err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(body))
+	if err != nil {
+		return "", utils.ErrorWithTrace(err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", "video/mp4")
+	req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", utils.ErrorWithTrace(err)
 	}
-	return fmt.Sprintf("https://www.youtube.com/embed/%s", resp.Id), nil
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", utils.ErrorWithTrace(uploadStatusError(resp))
+	}
+	uri := resp.Header.Get("Location")
+	if uri == "" {
+		return "", utils.ErrorWithTrace(fmt.Errorf("youtube did not return an upload session"))
+	}
+	return uri, nil
+}
+
+// Asks YouTube how much of the upload it already has
+func querySession(ctx context.Context, client *http.Client, session string, size int64) (*youtube.Video, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, nil)
+	if err != nil {
+		return nil, 0, utils.ErrorWithTrace(err)
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, utils.ErrorWithTrace(err)
+	}
+	defer resp.Body.Close()
+	return uploadProgress(resp)
+}
+
+func sendChunk(ctx context.Context, client *http.Client, session string, file *os.File, offset, size int64) (*youtube.Video, int64, error) {
+	length := min(chunkSize, size-offset)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, io.NewSectionReader(file, offset, length))
+	if err != nil {
+		return nil, 0, utils.ErrorWithTrace(err)
+	}
+	req.ContentLength = length
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, utils.ErrorWithTrace(err)
+	}
+	defer resp.Body.Close()
+	return uploadProgress(resp)
+}
+
+// A finished upload responds with the video, an unfinished one with a 308 and
+// the range of bytes received so far, which tells us where to send from next
+func uploadProgress(resp *http.Response) (*youtube.Video, int64, error) {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		video := &youtube.Video{}
+		if err := json.NewDecoder(resp.Body).Decode(video); err != nil {
+			return nil, 0, utils.ErrorWithTrace(err)
+		}
+		return video, 0, nil
+	case http.StatusPermanentRedirect:
+		received := resp.Header.Get("Range")
+		if received == "" {
+			return nil, 0, nil
+		}
+		var first, last int64
+		if _, err := fmt.Sscanf(received, "bytes=%d-%d", &first, &last); err != nil {
+			return nil, 0, utils.ErrorWithTrace(fmt.Errorf("unexpected upload range '%s': %w", received, err))
+		}
+		return nil, last + 1, nil
+	default:
+		return nil, 0, utils.ErrorWithTrace(uploadStatusError(resp))
+	}
+}
+
+// Leaves the session uri out of the error, anyone holding it can upload to the channel
+func uploadStatusError(resp *http.Response) error {
+	return &utils.HTTPStatusError{URL: "https://www.googleapis.com/upload/youtube/v3/videos", StatusCode: resp.StatusCode}
+}
+
+const PublisherName = "youtube"
+
+// Publishes videos to the channel the oauth token belongs to
+type Publisher struct{}
+
+func NewPublisher() (*Publisher, error) {
+	if err := InitService(); err != nil {
+		return nil, utils.ErrorWithTrace(err)
+	}
+	return &Publisher{}, nil
+}
+
+func (p *Publisher) Name() string {
+	return PublisherName
+}
+
+func (p *Publisher) Publish(ctx context.Context, filepath, title, description string, tags []string, onProgress func(sent, total int64)) (string, error) {
+	return UploadFile(ctx, filepath, title, description, tags, onProgress)
 }
 
 func InitService() error {
 	var err error
 	serviceMut.Lock()
 	defer serviceMut.Unlock()
-	service, err = GetService()
+	httpClient, err = GetHTTPClient()
 	if err != nil {
 		return utils.ErrorWithTrace(err)
 	}
@@ -216,7 +395,7 @@ func ServiceJanitor(duration time.Duration) {
 	ticker := time.NewTicker(duration)
 	for range ticker.C {
 		serviceMut.Lock()
-		service, err = GetService()
+		httpClient, err = GetHTTPClient()
 		if err != nil {
 			panic(err)
 		}